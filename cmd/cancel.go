@@ -2,10 +2,9 @@
 package cmd
 
 import (
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	"github.com/threefoldtech/grid3-go/deployer"
 	"github.com/threefoldtech/tf-grid-cli/internal/config"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
 )
 
 // cancelCmd represents the cancel command
@@ -13,20 +12,21 @@ var cancelCmd = &cobra.Command{
 	Use:   "cancel",
 	Short: "Cancel resources on Threefold grid",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
 		cfg, err := config.GetUserConfig()
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
-		t, err := deployer.NewTFPluginClient(cfg.Mnemonics, "sr25519", cfg.Network, "", "", "", 100, true, false)
+		t, err := newTFPluginClient(cfg)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 		err = t.CancelByProjectName(args[0])
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return errkind.Wrap(errkind.Chain, err)
 		}
+		return nil
 	},
 }
 
@@ -0,0 +1,133 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/threefoldtech/grid3-go/deployer"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+var (
+	// commandCtx is the context of the running command, done on interrupt or after --timeout
+	commandCtx = context.Background()
+
+	trackersMu sync.Mutex
+	// trackers record the contracts created by the grid clients of the running command
+	trackers   []*command.ContractTracker
+	rolledBack bool
+)
+
+// newTFPluginClient creates a grid client with the given configuration, recording the contracts it creates
+// so they are rolled back if the command is interrupted
+func newTFPluginClient(cfg config.Config) (deployer.TFPluginClient, error) {
+	t, err := deployer.NewTFPluginClient(cfg.Mnemonics, "sr25519", cfg.Network, "", "", "", 100, true, logLevel <= zerolog.DebugLevel)
+	// grid3-go replaces the global logger and level
+	applyLogger()
+	if err != nil {
+		return deployer.TFPluginClient{}, errkind.Wrap(clientErrorKind(err), err)
+	}
+	t = command.TimeCalls(t)
+	t, tracker := command.TrackContracts(t)
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
+	trackers = append(trackers, tracker)
+	return t, nil
+}
+
+// clientErrorKind tells chain connection errors of creating a grid client from configuration errors,
+// grid3-go only reports them by message
+func clientErrorKind(err error) errkind.Kind {
+	msg := err.Error()
+	if strings.Contains(msg, "could not get substrate client") || strings.Contains(msg, "failed to get twin") {
+		return errkind.Chain
+	}
+	return errkind.Config
+}
+
+// setCommandContext sets the context of the running command, canceled on the first interrupt and after timeout if it is set.
+// a second interrupt exits right away, logging the contracts created so far
+func setCommandContext(cmd *cobra.Command, timeout time.Duration) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout != 0 {
+		ctx, cancel = context.WithTimeout(cmd.Context(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(cmd.Context())
+	}
+	commandCtx = ctx
+	cmd.SetContext(ctx)
+
+	interrupts := make(chan os.Signal, 2)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupts
+		log.Warn().Msg("interrupted, stopping, press ctrl+c again to exit right away")
+		cancel()
+		<-interrupts
+		logRemainingContracts(createdContracts())
+		reportError(errors.New("interrupted"), errkind.Interrupted)
+		os.Exit(errkind.Interrupted.ExitCode())
+	}()
+}
+
+// rollbackContracts cancels the contracts created by the command if it was interrupted or timed out,
+// and logs and returns the ones that remain
+func rollbackContracts() []uint64 {
+	if commandCtx.Err() == nil {
+		return nil
+	}
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
+	if rolledBack {
+		return nil
+	}
+	rolledBack = true
+	var remaining []uint64
+	for _, tracker := range trackers {
+		if len(tracker.Created()) == 0 {
+			continue
+		}
+		log.Warn().Msgf("%s, canceling contracts created so far: %v", commandCtx.Err(), tracker.Created())
+		remaining = append(remaining, tracker.Rollback()...)
+	}
+	logRemainingContracts(remaining)
+	return remaining
+}
+
+// commitContracts keeps the contracts created so far if the command is interrupted afterwards
+func commitContracts() {
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
+	for _, tracker := range trackers {
+		tracker.Commit()
+	}
+}
+
+func createdContracts() []uint64 {
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
+	var created []uint64
+	for _, tracker := range trackers {
+		created = append(created, tracker.Created()...)
+	}
+	return created
+}
+
+func logRemainingContracts(contracts []uint64) {
+	if len(contracts) != 0 {
+		log.Error().Msgf("contracts created by this command remain, cancel them to stop being billed: %v", contracts)
+	}
+}
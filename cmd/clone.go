@@ -0,0 +1,74 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// cloneCmd represents the clone command
+var cloneCmd = &cobra.Command{
+	Use:   "clone <project>",
+	Short: "Deploy a copy of a vm or kubernetes project with another profile",
+	Long: `Deploy a copy of a vm or kubernetes project with the account and grid network of another profile.
+The project is read with the current configuration, and deployed with the profile saved by tf-grid login --profile.
+Nodes that are not up on the target network, or lack free resources, are replaced by nodes picked with the same resources.
+Other deployments of the project, like gateways and zdbs, are not cloned. Disk contents are not copied.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		profile, err := cmd.Flags().GetString("to-profile")
+		if err != nil {
+			return err
+		}
+		name, err := cmd.Flags().GetString("name")
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			name = args[0]
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		targetCfg, err := config.GetProfileConfig(profile)
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+		target, err := newTFPluginClient(targetCfg)
+		if err != nil {
+			return err
+		}
+
+		deploymentType, wgConfig, err := command.CloneProject(ctx, t, target, args[0], name)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("%s %s cloned as %s on %snet", deploymentType, args[0], name, targetCfg.Network)
+		if wgConfig != "" {
+			err = writeWGConfig(name, wgConfig)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+
+	cloneCmd.Flags().String("to-profile", "", "profile to deploy the copy with, saved using tf-grid login --profile")
+	err := cloneCmd.MarkFlagRequired("to-profile")
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+	cloneCmd.Flags().StringP("name", "n", "", "name of the copy, defaults to the project name")
+}
@@ -2,7 +2,16 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"github.com/threefoldtech/grid3-go/deployer"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/tf-grid-cli/internal/filters"
 )
 
 // deployCmd represents the deploy command
@@ -15,3 +24,51 @@ func init() {
 	rootCmd.AddCommand(deployCmd)
 
 }
+
+func addWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("wait", false, "wait until the deployment is ready to use")
+	cmd.Flags().Duration("wait-timeout", 10*time.Minute, "maximum time to wait for the deployment to be ready")
+}
+
+func parseWaitFlags(cmd *cobra.Command) (wait bool, timeout time.Duration, err error) {
+	wait, err = cmd.Flags().GetBool("wait")
+	if err != nil {
+		return
+	}
+	timeout, err = cmd.Flags().GetDuration("wait-timeout")
+	return
+}
+
+// waitReady waits for a deployment to be ready using probe, and marks the error of a deployment not ready in time as a wait timeout
+// the deployment is kept if waiting is interrupted or times out
+func waitReady(probe func() error) error {
+	commitContracts()
+	err := probe()
+	if errors.Is(err, command.ErrProbeTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return errkind.Wrap(errkind.WaitTimeout, err)
+	}
+	return err
+}
+
+func addExposeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("expose", "", "expose a service port through a gateway name in the form name:port")
+	cmd.Flags().Uint64("gateway-farm", 1, "farm id of the gateway exposing the service")
+}
+
+// exposeService deploys a gateway name in the project proxying to the exposed port and logs its url
+func exposeService(ctx context.Context, t deployer.TFPluginClient, projectName, gatewayName string, port uint16, gatewayFarm uint64, computedIP, yggIP string) error {
+	backend, err := command.ExposeBackend(computedIP, yggIP, port)
+	if err != nil {
+		return err
+	}
+	node, err := filters.GetAvailableNode(ctx, t.GridProxyClient, filters.BuildGatewayFilter(gatewayFarm), command.NodeChecker(t))
+	if err != nil {
+		return err
+	}
+	gateway, err := command.ExposeService(ctx, t, projectName, gatewayName, node, backend)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("url: https://%s", gateway.FQDN)
+	return nil
+}
@@ -4,6 +4,7 @@ package cmd
 import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
 	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
 )
 
@@ -65,5 +66,6 @@ func parseCommonGatewayFlags(cmd *cobra.Command) (
 	if err != nil {
 		return
 	}
+	err = command.ValidateBackends(zosBackends, tls)
 	return
 }
@@ -4,7 +4,6 @@ package cmd
 import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	"github.com/threefoldtech/grid3-go/deployer"
 	"github.com/threefoldtech/grid3-go/workloads"
 	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
 	"github.com/threefoldtech/tf-grid-cli/internal/config"
@@ -16,6 +15,7 @@ var deployGatewayFQDNCmd = &cobra.Command{
 	Use:   "fqdn",
 	Short: "Deploy a gateway FQDN proxy",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		name, tls, zosBackends, node, farm, err := parseCommonGatewayFlags(cmd)
 		if err != nil {
 			return err
@@ -24,6 +24,14 @@ var deployGatewayFQDNCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		wait, waitTimeout, err := parseWaitFlags(cmd)
+		if err != nil {
+			return err
+		}
+		verifyDNS, err := cmd.Flags().GetBool("verify-dns")
+		if err != nil {
+			return err
+		}
 		gateway := workloads.GatewayFQDNProxy{
 			Name:           name,
 			Backends:       zosBackends,
@@ -33,27 +41,47 @@ var deployGatewayFQDNCmd = &cobra.Command{
 		}
 		cfg, err := config.GetUserConfig()
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
-		t, err := deployer.NewTFPluginClient(cfg.Mnemonics, "sr25519", cfg.Network, "", "", "", 100, true, false)
+		t, err := newTFPluginClient(cfg)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 		if node == 0 {
 			node, err = filters.GetAvailableNode(
+				ctx,
 				t.GridProxyClient,
 				filters.BuildGatewayFilter(farm),
+				command.NodeChecker(t),
 			)
 			if err != nil {
-				log.Fatal().Err(err).Send()
+				return err
 			}
 		}
 		gateway.NodeID = node
-		err = command.DeployGatewayFQDN(t, gateway)
+		records, err := command.GatewayDNSRecords(t, node)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			log.Info().Msgf("dns record needed for %s: %s", fqdn, record)
+		}
+		if verifyDNS {
+			err = command.VerifyDNS(fqdn, records)
+			if err != nil {
+				return err
+			}
+		}
+		err = command.DeployGatewayFQDN(ctx, t, gateway)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 		log.Info().Msg("gateway fqdn deployed")
+		if wait {
+			return waitReady(func() error {
+				return command.WaitForHTTP(ctx, fqdn, waitTimeout)
+			})
+		}
 		return nil
 	},
 }
@@ -67,4 +95,7 @@ func init() {
 		log.Fatal().Err(err).Send()
 	}
 
+	deployGatewayFQDNCmd.Flags().Bool("verify-dns", false, "check the fqdn resolves to the gateway node before deploying")
+
+	addWaitFlags(deployGatewayFQDNCmd)
 }
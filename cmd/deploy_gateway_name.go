@@ -4,7 +4,6 @@ package cmd
 import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	"github.com/threefoldtech/grid3-go/deployer"
 	"github.com/threefoldtech/grid3-go/workloads"
 	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
 	"github.com/threefoldtech/tf-grid-cli/internal/config"
@@ -16,10 +15,19 @@ var deployGatewayNameCmd = &cobra.Command{
 	Use:   "name",
 	Short: "Deploy a gateway name proxy",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		name, tls, zosBackends, node, farm, err := parseCommonGatewayFlags(cmd)
 		if err != nil {
 			return err
 		}
+		domain, err := cmd.Flags().GetString("domain")
+		if err != nil {
+			return err
+		}
+		wait, waitTimeout, err := parseWaitFlags(cmd)
+		if err != nil {
+			return err
+		}
 		gateway := workloads.GatewayNameProxy{
 			Name:           name,
 			Backends:       zosBackends,
@@ -28,27 +36,49 @@ var deployGatewayNameCmd = &cobra.Command{
 		}
 		cfg, err := config.GetUserConfig()
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
-		t, err := deployer.NewTFPluginClient(cfg.Mnemonics, "sr25519", cfg.Network, "", "", "", 100, true, false)
+		t, err := newTFPluginClient(cfg)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
-		if node == 0 {
+		if domain != "" {
+			// the farm is only used to select a node serving the domain if it is explicitly set
+			if !cmd.Flags().Changed("farm") {
+				farm = 0
+			}
+			node, err = filters.GetGatewayNodeByDomain(
+				ctx,
+				t.GridProxyClient,
+				filters.BuildGatewaysFilter(farm, ""),
+				domain,
+				command.NodeChecker(t),
+			)
+			if err != nil {
+				return err
+			}
+		} else if node == 0 {
 			node, err = filters.GetAvailableNode(
+				ctx,
 				t.GridProxyClient,
 				filters.BuildGatewayFilter(farm),
+				command.NodeChecker(t),
 			)
 			if err != nil {
-				log.Fatal().Err(err).Send()
+				return err
 			}
 		}
 		gateway.NodeID = node
-		resGateway, err := command.DeployGatewayName(t, gateway)
+		resGateway, err := command.DeployGatewayName(ctx, t, gateway)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 		log.Info().Msgf("fqdn: %s", resGateway.FQDN)
+		if wait {
+			return waitReady(func() error {
+				return command.WaitForHTTP(ctx, resGateway.FQDN, waitTimeout)
+			})
+		}
 		return nil
 	},
 }
@@ -56,4 +86,7 @@ var deployGatewayNameCmd = &cobra.Command{
 func init() {
 	deployGatewayCmd.AddCommand(deployGatewayNameCmd)
 
+	deployGatewayNameCmd.Flags().String("domain", "", "gateway domain the name should be served on, see gateways list")
+	deployGatewayNameCmd.MarkFlagsMutuallyExclusive("domain", "node")
+	addWaitFlags(deployGatewayNameCmd)
 }
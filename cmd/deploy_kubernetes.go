@@ -4,13 +4,14 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	"github.com/threefoldtech/grid3-go/deployer"
 	"github.com/threefoldtech/grid3-go/workloads"
 	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
 	"github.com/threefoldtech/tf-grid-cli/internal/config"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
 	"github.com/threefoldtech/tf-grid-cli/internal/filters"
 )
 
@@ -21,6 +22,7 @@ var deployKubernetesCmd = &cobra.Command{
 	Use:   "kubernetes",
 	Short: "Deploy a kubernetes cluster",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		name, err := cmd.Flags().GetString("name")
 		if err != nil {
 			return err
@@ -31,7 +33,11 @@ var deployKubernetesCmd = &cobra.Command{
 		}
 		sshKey, err := os.ReadFile(sshFile)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
+		}
+		wait, waitTimeout, err := parseWaitFlags(cmd)
+		if err != nil {
+			return err
 		}
 		masterNode, err := cmd.Flags().GetUint32("master-node")
 		if err != nil {
@@ -65,6 +71,37 @@ var deployKubernetesCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		expose, err := cmd.Flags().GetString("expose")
+		if err != nil {
+			return err
+		}
+		gatewayFarm, err := cmd.Flags().GetUint64("gateway-farm")
+		if err != nil {
+			return err
+		}
+		var exposeName string
+		var exposePort uint16
+		if expose != "" {
+			exposeName, exposePort, err = command.ParseExpose(expose)
+			if err != nil {
+				return err
+			}
+			if !ipv4 && !ygg {
+				return errkind.Errorf(errkind.Validation, "expose needs a public ipv4 or a yggdrasil ip on the master")
+			}
+		}
+		networkRange, err := cmd.Flags().GetString("network-range")
+		if err != nil {
+			return err
+		}
+		ipRange, err := command.ParseNetworkRange(networkRange)
+		if err != nil {
+			return err
+		}
+		masterIP, err := cmd.Flags().GetString("master-ip")
+		if err != nil {
+			return err
+		}
 		master := workloads.K8sNode{
 			Name:      name,
 			CPU:       masterCPU,
@@ -74,6 +111,7 @@ var deployKubernetesCmd = &cobra.Command{
 			PublicIP:  ipv4,
 			PublicIP6: ipv6,
 			Planetary: ygg,
+			IP:        masterIP,
 		}
 
 		workerNumber, err := cmd.Flags().GetInt("workers-number")
@@ -116,47 +154,58 @@ var deployKubernetesCmd = &cobra.Command{
 
 		cfg, err := config.GetUserConfig()
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
-		t, err := deployer.NewTFPluginClient(cfg.Mnemonics, "sr25519", cfg.Network, "", "", "", 100, true, false)
+		t, err := newTFPluginClient(cfg)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
+		}
+		if expose != "" {
+			// checked before deploying so a taken name doesn't leave the deployment half done
+			err = command.CheckGatewayName(t, exposeName)
+			if err != nil {
+				return err
+			}
 		}
 
 		if masterNode == 0 {
 			masterNode, err = filters.GetAvailableNode(
+				ctx,
 				t.GridProxyClient,
 				filters.BuildK8sFilter(
 					master,
 					masterFarm,
 					1,
 				),
+				command.NodeChecker(t),
 			)
 			if err != nil {
-				log.Fatal().Err(err).Send()
+				return err
 			}
 		}
 		master.Node = masterNode
 
 		if workersNode == 0 && len(workers) > 0 {
 			workersNode, err = filters.GetAvailableNode(
+				ctx,
 				t.GridProxyClient,
 				filters.BuildK8sFilter(
 					workers[0],
 					workersFarm,
 					uint(len(workers)),
 				),
+				command.NodeChecker(t),
 			)
 			if err != nil {
-				log.Fatal().Err(err).Send()
+				return err
 			}
 		}
 		for i := 0; i < workerNumber; i++ {
 			workers[i].Node = workersNode
 		}
-		cluster, err := command.DeployKubernetesCluster(t, master, workers, string(sshKey))
+		cluster, err := command.DeployKubernetesCluster(ctx, t, master, workers, string(sshKey), ipRange)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 		if ipv4 {
 			log.Info().Msgf("master ipv4: %s", cluster.Master.ComputedIP)
@@ -167,6 +216,23 @@ var deployKubernetesCmd = &cobra.Command{
 		if ygg {
 			log.Info().Msgf("master yggdrasil ip: %s", cluster.Master.YggIP)
 		}
+		log.Info().Msgf("master private ip: %s", cluster.Master.IP)
+		if expose != "" {
+			err = exposeService(ctx, t, name, exposeName, exposePort, gatewayFarm, cluster.Master.ComputedIP, cluster.Master.YggIP)
+			if err != nil {
+				return err
+			}
+		}
+		if wait {
+			identity, err := command.FindSSHIdentity(string(sshKey))
+			if err != nil {
+				// the private key usually sits next to the public one
+				identity = strings.TrimSuffix(sshFile, ".pub")
+			}
+			return waitReady(func() error {
+				return command.WaitForK3s(ctx, *cluster.Master, identity, len(cluster.Workers)+1, waitTimeout)
+			})
+		}
 		return nil
 	},
 }
@@ -202,4 +268,10 @@ func init() {
 	deployKubernetesCmd.Flags().Bool("ipv4", false, "assign public ipv4 for master")
 	deployKubernetesCmd.Flags().Bool("ipv6", false, "assign public ipv6 for master")
 	deployKubernetesCmd.Flags().Bool("ygg", true, "assign yggdrasil ip for master")
+
+	deployKubernetesCmd.Flags().String("network-range", command.DefaultNetworkRange, "private ip range of the cluster network")
+	deployKubernetesCmd.Flags().String("master-ip", "", "private ip of the master, must be in the master node subnet")
+
+	addWaitFlags(deployKubernetesCmd)
+	addExposeFlags(deployKubernetesCmd)
 }
@@ -0,0 +1,62 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/subi"
+	"github.com/threefoldtech/substrate-client"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+type fakeSubstrate struct {
+	subi.SubstrateExt
+	created  uint64
+	canceled []uint64
+}
+
+func (f *fakeSubstrate) CreateNodeContract(identity substrate.Identity, node uint32, body string, hash string, publicIPs uint32, solutionProviderID *uint64) (uint64, error) {
+	f.created++
+	return f.created, nil
+}
+
+func (f *fakeSubstrate) EnsureContractCanceled(identity substrate.Identity, contractID uint64) error {
+	f.canceled = append(f.canceled, contractID)
+	return nil
+}
+
+func TestWaitReadyKeepsDeployment(t *testing.T) {
+	defer func(ctx context.Context) {
+		commandCtx, trackers, rolledBack = ctx, nil, false
+	}(commandCtx)
+
+	for _, probeErr := range []error{context.Canceled, context.DeadlineExceeded, command.ErrProbeTimeout} {
+		sub := &fakeSubstrate{}
+		client, tracker := command.TrackContracts(deployer.TFPluginClient{SubstrateConn: sub})
+		trackers, rolledBack = []*command.ContractTracker{tracker}, false
+		ctx, cancel := context.WithCancel(context.Background())
+		commandCtx = ctx
+
+		_, err := client.SubstrateConn.CreateNodeContract(nil, 1, "", "", 0, nil)
+		assert.NoError(t, err)
+		cancel()
+		err = waitReady(func() error { return probeErr })
+		assert.ErrorIs(t, err, probeErr)
+
+		assert.Empty(t, rollbackContracts())
+		assert.Empty(t, sub.canceled)
+	}
+}
+
+func TestWaitReadyErrorKind(t *testing.T) {
+	defer func() { trackers = nil }()
+
+	assert.NoError(t, waitReady(func() error { return nil }))
+	assert.Equal(t, errkind.WaitTimeout, errkind.Of(waitReady(func() error { return command.ErrProbeTimeout })))
+	assert.Equal(t, errkind.WaitTimeout, errkind.Of(waitReady(func() error { return context.DeadlineExceeded })))
+	assert.Equal(t, errkind.Interrupted, errkind.Of(waitReady(func() error { return context.Canceled })))
+}
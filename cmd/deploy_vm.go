@@ -7,10 +7,10 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	"github.com/threefoldtech/grid3-go/deployer"
 	"github.com/threefoldtech/grid3-go/workloads"
 	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
 	"github.com/threefoldtech/tf-grid-cli/internal/config"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
 	"github.com/threefoldtech/tf-grid-cli/internal/filters"
 )
 
@@ -22,6 +22,7 @@ var deployVMCmd = &cobra.Command{
 	Use:   "vm",
 	Short: "Deploy a vm",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		name, err := cmd.Flags().GetString("name")
 		if err != nil {
 			return err
@@ -32,7 +33,7 @@ var deployVMCmd = &cobra.Command{
 		}
 		sshKey, err := os.ReadFile(sshFile)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 		node, err := cmd.Flags().GetUint32("node")
 		if err != nil {
@@ -78,6 +79,57 @@ var deployVMCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		qsfsSize, err := cmd.Flags().GetInt("qsfs-size")
+		if err != nil {
+			return err
+		}
+		qsfsMount, err := cmd.Flags().GetString("qsfs-mount")
+		if err != nil {
+			return err
+		}
+		networkRange, err := cmd.Flags().GetString("network-range")
+		if err != nil {
+			return err
+		}
+		ipRange, err := command.ParseNetworkRange(networkRange)
+		if err != nil {
+			return err
+		}
+		networkName, err := cmd.Flags().GetString("network")
+		if err != nil {
+			return err
+		}
+		wait, waitTimeout, err := parseWaitFlags(cmd)
+		if err != nil {
+			return err
+		}
+		expose, err := cmd.Flags().GetString("expose")
+		if err != nil {
+			return err
+		}
+		gatewayFarm, err := cmd.Flags().GetUint64("gateway-farm")
+		if err != nil {
+			return err
+		}
+		var exposeName string
+		var exposePort uint16
+		if expose != "" {
+			exposeName, exposePort, err = command.ParseExpose(expose)
+			if err != nil {
+				return err
+			}
+			if !ipv4 && !ygg {
+				return errkind.Errorf(errkind.Validation, "expose needs a public ipv4 or a yggdrasil ip")
+			}
+		}
+		ip, err := cmd.Flags().GetString("ip")
+		if err != nil {
+			return err
+		}
+		wireguard, err := cmd.Flags().GetBool("wireguard")
+		if err != nil {
+			return err
+		}
 		vm := workloads.VM{
 			Name:       name,
 			EnvVars:    map[string]string{"SSH_KEY": string(sshKey)},
@@ -89,6 +141,7 @@ var deployVMCmd = &cobra.Command{
 			PublicIP:   ipv4,
 			PublicIP6:  ipv6,
 			Planetary:  ygg,
+			IP:         ip,
 		}
 		var mount workloads.Disk
 		if disk != 0 {
@@ -98,24 +151,71 @@ var deployVMCmd = &cobra.Command{
 		}
 		cfg, err := config.GetUserConfig()
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
-		t, err := deployer.NewTFPluginClient(cfg.Mnemonics, "sr25519", cfg.Network, "", "", "", 100, true, false)
+		t, err := newTFPluginClient(cfg)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
+		}
+		if expose != "" {
+			// checked before deploying so a taken name doesn't leave the deployment half done
+			err = command.CheckGatewayName(t, exposeName)
+			if err != nil {
+				return err
+			}
 		}
 		if node == 0 {
+			filter := filters.BuildVMFilter(vm, mount, farm)
+			if qsfsSize != 0 {
+				// qsfs reserves its cache and 1 gb of memory on the vm node
+				*filter.FreeSRU += command.QSFSCacheSize / 1024
+				*filter.FreeMRU++
+			}
 			node, err = filters.GetAvailableNode(
+				ctx,
+				t.GridProxyClient,
+				filter,
+				command.NodeChecker(t),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		var qsfs workloads.QSFS
+		var qsfsDeployments map[uint32]uint64
+		if qsfsSize != 0 {
+			zdbs, err := command.BuildQSFSZDBs(name, qsfsSize)
+			if err != nil {
+				return err
+			}
+			zdbNodes, err := filters.GetAvailableNodes(
+				ctx,
 				t.GridProxyClient,
-				filters.BuildVMFilter(vm, mount, farm),
+				filters.BuildZDBsFilter(zdbs, farm),
+				command.QSFSBackendNodes,
+				command.NodeChecker(t),
 			)
 			if err != nil {
-				log.Fatal().Err(err).Send()
+				return err
+			}
+			qsfs, qsfsDeployments, err = command.DeployQSFSBackends(ctx, t, name, zdbNodes, zdbs)
+			if err != nil {
+				return err
 			}
+			vm.Mounts = append(vm.Mounts, workloads.Mount{DiskName: qsfs.Name, MountPoint: qsfsMount})
+		}
+		var resVM workloads.VM
+		var wgConfig, wgProject string
+		if networkName != "" {
+			var network workloads.ZNet
+			resVM, network, err = command.DeployVMInNetwork(ctx, t, vm, mount, qsfs, node, networkName)
+			wgConfig, wgProject = network.AccessWGConfig, network.SolutionType
+		} else {
+			resVM, wgConfig, err = command.DeployVM(ctx, t, vm, mount, qsfs, node, ipRange, wireguard)
+			wgProject = name
 		}
-		resVM, err := command.DeployVM(t, vm, mount, node)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return command.CancelQSFSBackends(t, qsfsDeployments, err)
 		}
 
 		if ipv4 {
@@ -127,6 +227,24 @@ var deployVMCmd = &cobra.Command{
 		if ygg {
 			log.Info().Msgf("vm yggdrasil ip: %s", resVM.YggIP)
 		}
+		log.Info().Msgf("vm private ip: %s", resVM.IP)
+		if wgConfig != "" {
+			err = writeWGConfig(wgProject, wgConfig)
+			if err != nil {
+				return err
+			}
+		}
+		if expose != "" {
+			err = exposeService(ctx, t, vm.Name, exposeName, exposePort, gatewayFarm, resVM.ComputedIP, resVM.YggIP)
+			if err != nil {
+				return err
+			}
+		}
+		if wait {
+			return waitReady(func() error {
+				return command.WaitForVM(ctx, resVM, waitTimeout)
+			})
+		}
 		return nil
 	},
 }
@@ -154,6 +272,8 @@ func init() {
 	deployVMCmd.Flags().Int("memory", 1, "memory size in gb")
 	deployVMCmd.Flags().Int("rootfs", 2, "root filesystem size in gb")
 	deployVMCmd.Flags().Int("disk", 0, "disk size in gb mounted on /data")
+	deployVMCmd.Flags().Int("qsfs-size", 0, "qsfs size in gb, its zdbs are spread on multiple nodes")
+	deployVMCmd.Flags().String("qsfs-mount", "/storage", "mount point of qsfs in the vm")
 	deployVMCmd.Flags().String("flist", ubuntuFlist, "flist for vm")
 	deployVMCmd.Flags().String("entrypoint", ubuntuFlistEntrypoint, "entrypoint for vm")
 	// to ensure entrypoint is provided for custom flist
@@ -162,4 +282,14 @@ func init() {
 	deployVMCmd.Flags().Bool("ipv4", false, "assign public ipv4 for vm")
 	deployVMCmd.Flags().Bool("ipv6", false, "assign public ipv6 for vm")
 	deployVMCmd.Flags().Bool("ygg", true, "assign yggdrasil ip for vm")
+
+	deployVMCmd.Flags().String("network-range", command.DefaultNetworkRange, "private ip range of the vm network")
+	deployVMCmd.Flags().String("ip", "", "private ip of the vm, must be in the node subnet")
+	deployVMCmd.Flags().Bool("wireguard", false, "add wireguard access to the vm network and write its wg-quick config file")
+	deployVMCmd.Flags().String("network", "", "name of an existing network to deploy the vm in instead of creating one")
+	deployVMCmd.MarkFlagsMutuallyExclusive("network", "network-range")
+	deployVMCmd.MarkFlagsMutuallyExclusive("network", "wireguard")
+
+	addWaitFlags(deployVMCmd)
+	addExposeFlags(deployVMCmd)
 }
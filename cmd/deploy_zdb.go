@@ -0,0 +1,108 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/threefoldtech/grid3-go/workloads"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/tf-grid-cli/internal/filters"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// deployZDBCmd represents the deploy zdb command
+var deployZDBCmd = &cobra.Command{
+	Use:   "zdb",
+	Short: "Deploy a zdb",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		name, err := cmd.Flags().GetString("name")
+		if err != nil {
+			return err
+		}
+		node, err := cmd.Flags().GetUint32("node")
+		if err != nil {
+			return err
+		}
+		farm, err := cmd.Flags().GetUint64("farm")
+		if err != nil {
+			return err
+		}
+		size, err := cmd.Flags().GetInt("size")
+		if err != nil {
+			return err
+		}
+		mode, err := cmd.Flags().GetString("mode")
+		if err != nil {
+			return err
+		}
+		if err := zos.ZDBMode(mode).Valid(); err != nil {
+			return errkind.Errorf(errkind.Validation, "invalid zdb mode %s, must be one of: %s, %s", mode, zos.ZDBModeUser, zos.ZDBModeSeq)
+		}
+		password, err := cmd.Flags().GetString("password")
+		if err != nil {
+			return err
+		}
+		public, err := cmd.Flags().GetBool("public")
+		if err != nil {
+			return err
+		}
+		zdb := workloads.ZDB{
+			Name:     name,
+			Size:     size,
+			Mode:     mode,
+			Password: password,
+			Public:   public,
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+		if node == 0 {
+			node, err = filters.GetAvailableNode(
+				ctx,
+				t.GridProxyClient,
+				filters.BuildZDBFilter(zdb, farm),
+				command.NodeChecker(t),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		resZDB, err := command.DeployZDB(ctx, t, zdb, node)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("zdb namespace: %s", resZDB.Namespace)
+		log.Info().Msgf("zdb port: %d", resZDB.Port)
+		for _, ip := range resZDB.IPs {
+			log.Info().Msgf("zdb ip: %s", ip)
+		}
+		return nil
+	},
+}
+
+func init() {
+	deployCmd.AddCommand(deployZDBCmd)
+
+	deployZDBCmd.Flags().StringP("name", "n", "", "name of the zdb")
+	err := deployZDBCmd.MarkFlagRequired("name")
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+
+	deployZDBCmd.Flags().Uint32("node", 0, "node id zdb should be deployed on")
+	deployZDBCmd.Flags().Uint64("farm", 1, "farm id zdb should be deployed on")
+	deployZDBCmd.MarkFlagsMutuallyExclusive("node", "farm")
+
+	deployZDBCmd.Flags().Int("size", 1, "zdb size in gb")
+	deployZDBCmd.Flags().String("mode", zos.ZDBModeUser, "zdb mode (user, seq)")
+	deployZDBCmd.Flags().String("password", "", "zdb namespace password")
+	deployZDBCmd.Flags().Bool("public", false, "make zdb namespace public")
+}
@@ -0,0 +1,59 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// eventsCmd represents the events command
+var eventsCmd = &cobra.Command{
+	Use:   "events <project>",
+	Short: "Show the workload state transitions of a project from its nodes change history",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		follow, err := cmd.Flags().GetBool("follow")
+		if err != nil {
+			return err
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		if follow {
+			err = command.FollowEvents(ctx, t, args[0], func(event command.Event) error {
+				log.Info().Msg(event.String())
+				return nil
+			})
+			if err != nil && !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		}
+		events, err := command.GetEvents(ctx, t, args[0])
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			log.Info().Msg(event.String())
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+
+	eventsCmd.Flags().BoolP("follow", "f", false, "keep polling the nodes and show new events")
+}
@@ -0,0 +1,18 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// gatewayCmd represents the gateway command
+var gatewayCmd = &cobra.Command{
+	Use:     "gateway",
+	Aliases: []string{"gateways"},
+	Short:   "Manage gateways deployed on Threefold grid",
+}
+
+func init() {
+	rootCmd.AddCommand(gatewayCmd)
+
+}
@@ -0,0 +1,48 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// gatewayDNSCheckCmd represents the gateway dns-check command
+var gatewayDNSCheckCmd = &cobra.Command{
+	Use:   "dns-check",
+	Short: "Check the fqdn of a deployed gateway fqdn resolves to its gateway node",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		gateway, err := command.GetGatewayFQDN(t, args[0])
+		if err != nil {
+			return err
+		}
+		records, err := command.GatewayDNSRecords(t, gateway.NodeID)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			log.Info().Msgf("dns record needed for %s: %s", gateway.FQDN, record)
+		}
+		err = command.VerifyDNS(gateway.FQDN, records)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("%s resolves to gateway node %d", gateway.FQDN, gateway.NodeID)
+		return nil
+	},
+}
+
+func init() {
+	gatewayCmd.AddCommand(gatewayDNSCheckCmd)
+}
@@ -0,0 +1,54 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// gatewayListCmd represents the gateways list command
+var gatewayListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List gateway nodes with their domains",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		farm, err := cmd.Flags().GetUint64("farm")
+		if err != nil {
+			return err
+		}
+		country, err := cmd.Flags().GetString("country")
+		if err != nil {
+			return err
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		gateways, err := command.ListGateways(t, farm, country)
+		if err != nil {
+			return err
+		}
+		s, err := json.MarshalIndent(gateways, "", "\t")
+		if err != nil {
+			return err
+		}
+		log.Info().Msg("gateways:\n" + string(s))
+		return nil
+	},
+}
+
+func init() {
+	gatewayCmd.AddCommand(gatewayListCmd)
+
+	gatewayListCmd.Flags().Uint64("farm", 0, "only list gateways of this farm id")
+	gatewayListCmd.Flags().String("country", "", "only list gateways in this country")
+}
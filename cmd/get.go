@@ -2,15 +2,105 @@
 package cmd
 
 import (
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"github.com/threefoldtech/grid3-go/deployer"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
 )
 
 // getCmd represents the get command
 var getCmd = &cobra.Command{
-	Use:   "get",
+	Use:   "get [project]",
 	Short: "Get a deployed resource from Threefold grid",
+	Long:  "Get a deployed resource from Threefold grid, or every workload deployed under a project name",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if len(args) == 0 {
+			if err := cmd.Help(); err != nil {
+				return err
+			}
+			return nil
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		onlyErrors, err := cmd.Flags().GetBool("only-errors")
+		if err != nil {
+			return err
+		}
+		project, err := command.GetProject(ctx, t, args[0])
+		if err != nil {
+			return err
+		}
+		if !onlyErrors {
+			s, err := json.MarshalIndent(project, "", "\t")
+			if err != nil {
+				return err
+			}
+			log.Info().Msg("project:\n" + string(s))
+		}
+		return reportWorkloadStates(project.WorkloadStates(), onlyErrors)
+	},
 }
 
 func init() {
 	rootCmd.AddCommand(getCmd)
+
+	getCmd.PersistentFlags().Bool("only-errors", false, "only show the workloads in error")
+}
+
+// getWorkloadStates gets the state of the workloads of a deployment from its node,
+// and returns whether only the failed workloads should be shown
+func getWorkloadStates(cmd *cobra.Command, t deployer.TFPluginClient, name, deploymentType string) ([]command.WorkloadState, bool, error) {
+	onlyErrors, err := cmd.Flags().GetBool("only-errors")
+	if err != nil {
+		return nil, false, err
+	}
+	states, err := command.GetWorkloadStates(cmd.Context(), t, name, deploymentType)
+	if err != nil {
+		return nil, false, err
+	}
+	return states, onlyErrors, nil
+}
+
+// getError reports the failed workloads if a deployment could not be loaded because of them
+func getError(err error, states []command.WorkloadState) error {
+	if err == nil {
+		return nil
+	}
+	if len(command.FailedWorkloads(states)) != 0 {
+		log.Error().Err(err).Send()
+		return reportWorkloadStates(states, true)
+	}
+	return err
+}
+
+// reportWorkloadStates logs the state of workloads and returns a workload error if any of them failed
+func reportWorkloadStates(states []command.WorkloadState, onlyErrors bool) error {
+	for _, state := range states {
+		if state.Failed() {
+			log.Error().Msgf("%s %s of deployment %s on node %d: %s: %s", state.Type, state.Name, state.Deployment, state.NodeID, state.State, state.Error)
+		} else if !onlyErrors {
+			log.Info().Msgf("%s %s of deployment %s on node %d: %s", state.Type, state.Name, state.Deployment, state.NodeID, state.State)
+		}
+	}
+	failed := command.FailedWorkloads(states)
+	if len(failed) == 0 {
+		if onlyErrors {
+			log.Info().Msg("no workloads in error")
+		}
+		return nil
+	}
+	return errkind.Errorf(errkind.WorkloadError, "%d of %d workloads failed", len(failed), len(states))
 }
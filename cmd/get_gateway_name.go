@@ -6,7 +6,6 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	"github.com/threefoldtech/grid3-go/deployer"
 	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
 	"github.com/threefoldtech/tf-grid-cli/internal/config"
 )
@@ -15,25 +14,33 @@ import (
 var getGatewayNameCmd = &cobra.Command{
 	Use:   "name",
 	Short: "Get deployed gateway name",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.GetUserConfig()
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
-		t, err := deployer.NewTFPluginClient(cfg.Mnemonics, "sr25519", cfg.Network, "", "", "", 100, true, false)
+		t, err := newTFPluginClient(cfg)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 
+		states, onlyErrors, err := getWorkloadStates(cmd, t, args[0], "Gateway Name")
+		if err != nil {
+			return err
+		}
+		if onlyErrors {
+			return reportWorkloadStates(states, true)
+		}
 		gateway, err := command.GetGatewayName(t, args[0])
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return getError(err, states)
 		}
 		s, err := json.MarshalIndent(gateway, "", "\t")
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 		log.Info().Msg("gateway name:\n" + string(s))
+		return reportWorkloadStates(states, false)
 	},
 }
 
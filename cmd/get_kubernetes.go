@@ -6,7 +6,6 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	"github.com/threefoldtech/grid3-go/deployer"
 	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
 	"github.com/threefoldtech/tf-grid-cli/internal/config"
 )
@@ -16,26 +15,41 @@ var getKubernetesCmd = &cobra.Command{
 	Use:   "kubernetes",
 	Short: "Get deployed kubernetes",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		cfg, err := config.GetUserConfig()
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
-		t, err := deployer.NewTFPluginClient(cfg.Mnemonics, "sr25519", cfg.Network, "", "", "", 100, true, false)
+		t, err := newTFPluginClient(cfg)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 
+		states, onlyErrors, err := getWorkloadStates(cmd, t, args[0], "kubernetes")
+		if err != nil {
+			return err
+		}
+		if onlyErrors {
+			return reportWorkloadStates(states, true)
+		}
 		cluster, err := command.GetK8sCluster(t, args[0])
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return getError(err, states)
 		}
 		s, err := json.MarshalIndent(cluster, "", "\t")
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 		log.Info().Msg("k8s cluster:\n" + string(s))
-
+		subnets, err := command.GetNetworkSubnets(ctx, t, cluster.NetworkName)
+		if err != nil {
+			return err
+		}
+		for node, subnet := range subnets {
+			log.Info().Msgf("node %d subnet: %s", node, subnet)
+		}
+		return reportWorkloadStates(states, false)
 	},
 }
 
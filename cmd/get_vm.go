@@ -6,7 +6,6 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	"github.com/threefoldtech/grid3-go/deployer"
 	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
 	"github.com/threefoldtech/tf-grid-cli/internal/config"
 )
@@ -16,26 +15,44 @@ var getVMCmd = &cobra.Command{
 	Use:   "vm",
 	Short: "Get deployed vm",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		cfg, err := config.GetUserConfig()
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
-		t, err := deployer.NewTFPluginClient(cfg.Mnemonics, "sr25519", cfg.Network, "", "", "", 100, true, false)
+		t, err := newTFPluginClient(cfg)
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 
+		states, onlyErrors, err := getWorkloadStates(cmd, t, args[0], "vm")
+		if err != nil {
+			return err
+		}
+		if onlyErrors {
+			return reportWorkloadStates(states, true)
+		}
 		vm, err := command.GetVM(t, args[0])
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return getError(err, states)
 		}
-		s, err := json.MarshalIndent(vm, "", "\t")
+		s, err := json.MarshalIndent(command.RedactDeployment(vm), "", "\t")
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
 		log.Info().Msg("vm:\n" + string(s))
-
+		for _, qsfs := range vm.QSFS {
+			log.Info().Msgf("qsfs %s metrics endpoint: %s", qsfs.Name, qsfs.MetricsEndpoint)
+		}
+		subnets, err := command.GetNetworkSubnets(ctx, t, vm.NetworkName)
+		if err != nil {
+			return err
+		}
+		for node, subnet := range subnets {
+			log.Info().Msgf("node %d subnet: %s", node, subnet)
+		}
+		return reportWorkloadStates(states, false)
 	},
 }
 
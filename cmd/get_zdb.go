@@ -0,0 +1,50 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// getZDBCmd represents the get zdb command
+var getZDBCmd = &cobra.Command{
+	Use:   "zdb",
+	Short: "Get deployed zdb",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		states, onlyErrors, err := getWorkloadStates(cmd, t, args[0], "vm")
+		if err != nil {
+			return err
+		}
+		if onlyErrors {
+			return reportWorkloadStates(states, true)
+		}
+		zdb, err := command.GetZDB(t, args[0])
+		if err != nil {
+			return getError(err, states)
+		}
+		s, err := json.MarshalIndent(command.RedactZDB(zdb), "", "\t")
+		if err != nil {
+			return err
+		}
+		log.Info().Msg("zdb:\n" + string(s))
+		return reportWorkloadStates(states, false)
+	},
+}
+
+func init() {
+	getCmd.AddCommand(getZDBCmd)
+}
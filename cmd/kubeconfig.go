@@ -0,0 +1,69 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// kubeconfigCmd represents the kubeconfig command
+var kubeconfigCmd = &cobra.Command{
+	Use:   "kubeconfig",
+	Short: "Fetch the kubeconfig of a deployed kubernetes cluster",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		merge, err := cmd.Flags().GetBool("merge")
+		if err != nil {
+			return err
+		}
+		useYgg, err := cmd.Flags().GetBool("use-ygg")
+		if err != nil {
+			return err
+		}
+		identity, err := cmd.Flags().GetString("identity")
+		if err != nil {
+			return err
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		kubeconfig, err := command.GetKubeconfig(t, args[0], identity, useYgg)
+		if err != nil {
+			return err
+		}
+		path := fmt.Sprintf("%s.yaml", args[0])
+		if merge {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return err
+			}
+			path = filepath.Join(home, ".kube", "config")
+		}
+		err = command.WriteKubeconfig(kubeconfig, path, merge)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("kubeconfig written to %s with context %s", path, kubeconfig.CurrentContext)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(kubeconfigCmd)
+
+	kubeconfigCmd.Flags().Bool("merge", false, "merge the kubeconfig into ~/.kube/config instead of writing <cluster>.yaml")
+	kubeconfigCmd.Flags().Bool("use-ygg", false, "use the master yggdrasil ip as the cluster server address")
+	kubeconfigCmd.Flags().StringP("identity", "i", "", "path to the private ssh key, the key matching the deployed one in ~/.ssh is used if not set")
+}
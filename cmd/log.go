@@ -0,0 +1,92 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+// log formats
+const (
+	consoleLog = "console"
+	jsonLog    = "json"
+)
+
+// logOptions are set from the global logging flags
+var logOptions struct {
+	level  string
+	format string
+	file   string
+	quiet  bool
+	debug  bool
+}
+
+var (
+	logger   = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	logLevel = zerolog.InfoLevel
+)
+
+// setupLogger builds the logger from the logging flags and applies it
+func setupLogger() error {
+	level, err := zerolog.ParseLevel(logOptions.level)
+	if err != nil || level == zerolog.NoLevel {
+		return errkind.Errorf(errkind.Validation, "invalid log level %q, expected trace, debug, info, warn or error", logOptions.level)
+	}
+	if logOptions.debug {
+		level = zerolog.DebugLevel
+	}
+	if logOptions.format != consoleLog && logOptions.format != jsonLog {
+		return errkind.Errorf(errkind.Validation, "invalid log format %q, expected %s or %s", logOptions.format, consoleLog, jsonLog)
+	}
+
+	var stderr io.Writer = os.Stderr
+	if logOptions.format == consoleLog {
+		stderr = zerolog.ConsoleWriter{Out: os.Stderr}
+	}
+	// quiet only silences the terminal, the log file keeps every log of the level
+	writer := stderr
+	if logOptions.quiet {
+		writer = minLevelWriter{Writer: stderr, min: zerolog.ErrorLevel}
+	}
+	if logOptions.file != "" {
+		file, err := os.OpenFile(logOptions.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return errkind.Wrap(errkind.Config, errors.Wrap(err, "could not open log file"))
+		}
+		var fileWriter io.Writer = file
+		if logOptions.format == consoleLog {
+			fileWriter = zerolog.ConsoleWriter{Out: file, NoColor: true}
+		}
+		writer = zerolog.MultiLevelWriter(writer, fileWriter)
+	}
+
+	logger = zerolog.New(writer).With().Timestamp().Logger()
+	logLevel = level
+	applyLogger()
+	return nil
+}
+
+// applyLogger sets the configured logger and level as the global ones,
+// it is called again after creating a grid client as grid3-go replaces them
+func applyLogger() {
+	log.Logger = logger
+	zerolog.SetGlobalLevel(logLevel)
+}
+
+// minLevelWriter drops the logs under a level
+type minLevelWriter struct {
+	io.Writer
+	min zerolog.Level
+}
+
+func (w minLevelWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < w.min {
+		return len(p), nil
+	}
+	return w.Write(p)
+}
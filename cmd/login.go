@@ -2,7 +2,6 @@
 package cmd
 
 import (
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
 )
@@ -11,14 +10,21 @@ import (
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Login with mnemonics to a grid network",
-	Run: func(cmd *cobra.Command, args []string) {
-		err := command.Login()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profile, err := cmd.Flags().GetString("profile")
 		if err != nil {
-			log.Fatal().Err(err).Send()
+			return err
 		}
+		err = command.Login(profile)
+		if err != nil {
+			return err
+		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(loginCmd)
+
+	loginCmd.Flags().String("profile", "", "save the configuration as a named profile, used by commands like clone")
 }
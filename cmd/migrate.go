@@ -0,0 +1,65 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate <project>",
+	Short: "Move a vm or kubernetes project to another node or farm",
+	Long: `Move a vm or kubernetes project to another node or farm.
+A copy of the project is deployed on the new node and checked to be healthy,
+gateways proxying to the project are switched to the copy, then the old deployments are canceled.
+Disk contents are not copied, the copy starts with empty disks.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		node, err := cmd.Flags().GetUint32("to-node")
+		if err != nil {
+			return err
+		}
+		farm, err := cmd.Flags().GetUint64("to-farm")
+		if err != nil {
+			return err
+		}
+		if node == 0 && farm == 0 {
+			return errkind.Errorf(errkind.Validation, "one of --to-node or --to-farm is required")
+		}
+		verifyTimeout, err := cmd.Flags().GetDuration("verify-timeout")
+		if err != nil {
+			return err
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		deploymentType, err := command.MigrateProject(ctx, t, args[0], node, farm, verifyTimeout)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("%s %s migrated, disk contents were not copied", deploymentType, args[0])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().Uint32("to-node", 0, "node id to move the project to")
+	migrateCmd.Flags().Uint64("to-farm", 0, "farm id to move the project to")
+	migrateCmd.MarkFlagsMutuallyExclusive("to-node", "to-farm")
+	migrateCmd.Flags().Duration("verify-timeout", 10*time.Minute, "maximum time to wait for the copy to answer on ssh before switching, 0 to only check its workloads")
+}
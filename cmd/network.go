@@ -0,0 +1,32 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// networkCmd represents the network command
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Manage networks of deployments on Threefold grid",
+}
+
+func init() {
+	rootCmd.AddCommand(networkCmd)
+}
+
+// writeWGConfig writes a wg-quick config file named after the project in the current directory
+func writeWGConfig(projectName, wgConfig string) error {
+	path := fmt.Sprintf("%s.conf", projectName)
+	err := os.WriteFile(path, []byte(wgConfig), 0600)
+	if err != nil {
+		return errors.Wrapf(err, "could not write wireguard config file %s", path)
+	}
+	log.Info().Msgf("wireguard config written to %s, connect using: wg-quick up ./%s", path, path)
+	return nil
+}
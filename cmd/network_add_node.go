@@ -0,0 +1,54 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// networkAddNodeCmd represents the network add-node command
+var networkAddNodeCmd = &cobra.Command{
+	Use:   "add-node",
+	Short: "Extend a deployed network to a new node",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		node, err := cmd.Flags().GetUint32("node")
+		if err != nil {
+			return err
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		network, err := command.AddNetworkNode(ctx, t, args[0], node)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("network %s nodes: %v", network.Name, network.Nodes)
+		if network.AccessWGConfig != "" {
+			err = writeWGConfig(network.SolutionType, network.AccessWGConfig)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	networkCmd.AddCommand(networkAddNodeCmd)
+
+	networkAddNodeCmd.Flags().Uint32("node", 0, "node id to add to the network")
+	err := networkAddNodeCmd.MarkFlagRequired("node")
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+}
@@ -0,0 +1,44 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// networkGetCmd represents the network get command
+var networkGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Get a deployed network with its nodes, subnets, access point and workloads",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		network, err := command.GetNetwork(ctx, t, args[0])
+		if err != nil {
+			return err
+		}
+		s, err := json.MarshalIndent(network, "", "\t")
+		if err != nil {
+			return err
+		}
+		log.Info().Msg("network:\n" + string(s))
+		return nil
+	},
+}
+
+func init() {
+	networkCmd.AddCommand(networkGetCmd)
+}
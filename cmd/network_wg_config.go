@@ -0,0 +1,40 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// networkWGConfigCmd represents the network wg-config command
+var networkWGConfigCmd = &cobra.Command{
+	Use:   "wg-config",
+	Short: "Add wireguard access to a project network and write its wg-quick config file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		wgConfig, err := command.NetworkWGConfig(ctx, t, args[0])
+		if err != nil {
+			return err
+		}
+		err = writeWGConfig(args[0], wgConfig)
+		if err != nil {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	networkCmd.AddCommand(networkWGConfigCmd)
+}
@@ -0,0 +1,42 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+// output formats of command errors
+const (
+	textOutput = "text"
+	jsonOutput = "json"
+)
+
+// outputFormat is the format command errors are reported in
+var outputFormat = textOutput
+
+type errorBody struct {
+	Error struct {
+		Kind     string `json:"kind"`
+		ExitCode int    `json:"exit_code"`
+		Message  string `json:"message"`
+	} `json:"error"`
+}
+
+// reportError logs a command error, or writes it as a json body to stdout in json output mode
+func reportError(err error, kind errkind.Kind) {
+	if outputFormat != jsonOutput {
+		log.Error().Err(err).Send()
+		return
+	}
+	var body errorBody
+	body.Error.Kind = kind.String()
+	body.Error.ExitCode = kind.ExitCode()
+	body.Error.Message = err.Error()
+	if err := json.NewEncoder(os.Stdout).Encode(body); err != nil {
+		log.Error().Err(err).Send()
+	}
+}
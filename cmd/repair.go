@@ -0,0 +1,50 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// repairCmd represents the repair command
+var repairCmd = &cobra.Command{
+	Use:   "repair <project>",
+	Short: "Redeploy the workloads in error of a project",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		repaired, skipped, err := command.RepairProject(ctx, t, args[0])
+		for _, state := range repaired {
+			if state.Failed() {
+				log.Info().Msgf("repaired %s %s of deployment %s on node %d, it failed with: %s", state.Type, state.Name, state.Deployment, state.NodeID, state.Error)
+			} else {
+				log.Info().Msgf("redeployed %s %s of deployment %s on node %d", state.Type, state.Name, state.Deployment, state.NodeID)
+			}
+		}
+		for _, state := range skipped {
+			log.Warn().Msgf("can't repair %s %s of deployment %s on node %d, it is %s: %s", state.Type, state.Name, state.Deployment, state.NodeID, state.State, state.Error)
+		}
+		if err != nil {
+			return err
+		}
+		if len(repaired) == 0 && len(skipped) == 0 {
+			log.Info().Msg("no workloads in error")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(repairCmd)
+}
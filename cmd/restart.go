@@ -0,0 +1,17 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// restartCmd represents the restart command
+var restartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Restart vms deployed on Threefold grid",
+}
+
+func init() {
+	rootCmd.AddCommand(restartCmd)
+
+}
@@ -0,0 +1,75 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+// restartKubernetesCmd represents the restart kubernetes command
+var restartKubernetesCmd = &cobra.Command{
+	Use:   "kubernetes",
+	Short: "Restart the nodes of a deployed kubernetes cluster",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		node, err := cmd.Flags().GetString("node")
+		if err != nil {
+			return err
+		}
+		identity, err := cmd.Flags().GetString("identity")
+		if err != nil {
+			return err
+		}
+		wait, waitTimeout, err := parseWaitFlags(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := command.RestartK8sCluster(ctx, t, args[0], node)
+		if err != nil {
+			return err
+		}
+		if node != "" {
+			log.Info().Msgf("kubernetes node %s of cluster %s restarted", node, args[0])
+		} else {
+			log.Info().Msgf("kubernetes cluster %s restarted", args[0])
+		}
+		if wait {
+			if identity == "" {
+				target, err := command.GetSSHTarget(t, args[0], "")
+				if err != nil {
+					return err
+				}
+				identity, err = command.FindSSHIdentity(target.PublicKey)
+				if err != nil {
+					return errkind.Wrap(errkind.Config, errors.Wrap(err, "use --identity to set the private key used to wait for the cluster"))
+				}
+			}
+			return waitReady(func() error {
+				return command.WaitForK3s(ctx, *cluster.Master, identity, len(cluster.Workers)+1, waitTimeout)
+			})
+		}
+		return nil
+	},
+}
+
+func init() {
+	restartCmd.AddCommand(restartKubernetesCmd)
+
+	restartKubernetesCmd.Flags().String("node", "", "name of the kubernetes node to restart, all nodes are restarted if not set")
+	restartKubernetesCmd.Flags().StringP("identity", "i", "", "path to the private ssh key used to wait for the cluster, the key matching the deployed one in ~/.ssh is used if not set")
+	addWaitFlags(restartKubernetesCmd)
+}
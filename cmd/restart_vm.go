@@ -0,0 +1,49 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// restartVMCmd represents the restart vm command
+var restartVMCmd = &cobra.Command{
+	Use:   "vm",
+	Short: "Restart a deployed vm",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		wait, waitTimeout, err := parseWaitFlags(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		vm, err := command.RestartVM(ctx, t, args[0])
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("vm %s restarted", vm.Name)
+		if wait {
+			return waitReady(func() error {
+				return command.WaitForVM(ctx, vm, waitTimeout)
+			})
+		}
+		return nil
+	},
+}
+
+func init() {
+	restartCmd.AddCommand(restartVMCmd)
+
+	addWaitFlags(restartVMCmd)
+}
@@ -2,29 +2,71 @@
 package cmd
 
 import (
+	"context"
 	"os"
 
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "tf-grid",
 	Short: "A cli for interacting with Threefold Grid",
+	// errors are reported by Execute with their exit code
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupLogger(); err != nil {
+			return err
+		}
+		if outputFormat != textOutput && outputFormat != jsonOutput {
+			return errkind.Errorf(errkind.Validation, "invalid output format %q, expected %s or %s", outputFormat, textOutput, jsonOutput)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			return err
+		}
+		// flags and arguments are valid, later errors are not usage errors
+		cmd.SilenceUsage = true
+		commandStarted = true
+		setCommandContext(cmd, timeout)
+		return nil
+	},
 }
 
+// commandStarted is set once the flags and arguments of the command are parsed
+var commandStarted bool
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// It exits with the exit code of the kind of error the command failed with.
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	err := rootCmd.ExecuteContext(context.Background())
+	if err == nil {
+		return
+	}
+	kind := errkind.Of(err)
+	if !commandStarted && kind == errkind.Unknown {
+		kind = errkind.Validation
 	}
+	if remaining := rollbackContracts(); len(remaining) != 0 {
+		kind = errkind.PartialFailure
+	}
+	reportError(err, kind)
+	os.Exit(kind.ExitCode())
 }
 
 func init() {
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	applyLogger()
+
+	rootCmd.PersistentFlags().Duration("timeout", 0, "maximum time the command can run, contracts it created are canceled when it is reached, 0 for no timeout")
+	rootCmd.PersistentFlags().StringVar(&logOptions.level, "log-level", zerolog.InfoLevel.String(), "minimum level of the logs, one of trace, debug, info, warn and error")
+	rootCmd.PersistentFlags().StringVar(&logOptions.format, "log-format", consoleLog, "format of the logs, console or json")
+	rootCmd.PersistentFlags().StringVar(&logOptions.file, "log-file", "", "also append the logs to a file, the terminal verbosity set by --quiet doesn't apply to it")
+	rootCmd.PersistentFlags().BoolVarP(&logOptions.quiet, "quiet", "q", false, "only show errors on the terminal")
+	rootCmd.PersistentFlags().BoolVar(&logOptions.debug, "debug", false, "show debug logs, including the duration of rmb, substrate and grid proxy calls")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", textOutput, "format of command errors, text or json to write them as a json body to stdout")
+	rootCmd.PersistentFlags().BoolVar(&command.CheckNodes, "node-check", true, "check candidate nodes answer over rmb before deploying on them, disable with --node-check=false")
 }
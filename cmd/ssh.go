@@ -0,0 +1,86 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+// sshCmd represents the ssh command
+var sshCmd = &cobra.Command{
+	Use:   "ssh <project> [-- command]",
+	Short: "Connect with ssh to a deployed vm or kubernetes node",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		node, err := cmd.Flags().GetString("node")
+		if err != nil {
+			return err
+		}
+		identity, err := cmd.Flags().GetString("identity")
+		if err != nil {
+			return err
+		}
+		user, err := cmd.Flags().GetString("user")
+		if err != nil {
+			return err
+		}
+		var remoteCommand []string
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			if dash != 1 {
+				return errkind.Errorf(errkind.Validation, "expected one project name before --, got %d", dash)
+			}
+			remoteCommand = args[dash:]
+		} else if len(args) != 1 {
+			return errkind.Errorf(errkind.Validation, "expected one project name, got %d, use -- before the remote command", len(args))
+		}
+
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		target, err := command.GetSSHTarget(t, args[0], node)
+		if err != nil {
+			return err
+		}
+		if identity == "" {
+			identity, err = command.FindSSHIdentity(target.PublicKey)
+			if err != nil {
+				return errkind.Wrap(errkind.Config, errors.Wrap(err, "use --identity to set the private key"))
+			}
+		}
+		log.Info().Msgf("connecting to %s on %s", target.Name, target.Address)
+
+		ssh := exec.Command("ssh", command.SSHArgs(target, identity, user, remoteCommand...)...)
+		ssh.Stdin = os.Stdin
+		ssh.Stdout = os.Stdout
+		ssh.Stderr = os.Stderr
+		err = ssh.Run()
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+		if err != nil {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sshCmd)
+
+	sshCmd.Flags().String("node", "", "name of the kubernetes node to connect to, master is used if not set")
+	sshCmd.Flags().StringP("identity", "i", "", "path to the private ssh key, the key matching the deployed one in ~/.ssh is used if not set")
+	sshCmd.Flags().String("user", "root", "user to connect with")
+}
@@ -0,0 +1,17 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// updateCmd represents the update command
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update resources deployed on Threefold grid",
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+
+}
@@ -0,0 +1,46 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// updateGatewayCmd represents the update gateway command
+var updateGatewayCmd = &cobra.Command{
+	Use:   "gateway",
+	Short: "Update a gateway proxy",
+}
+
+func init() {
+	updateCmd.AddCommand(updateGatewayCmd)
+
+	updateGatewayCmd.PersistentFlags().StringSlice("backends", []string{}, "new backends for the gateway")
+	updateGatewayCmd.PersistentFlags().Bool("tls", false, "enable or disable tls passthrough")
+}
+
+// parseUpdateGatewayFlags returns the changed gateway flags, unchanged ones are nil
+func parseUpdateGatewayFlags(cmd *cobra.Command) (zosBackends []zos.Backend, tls *bool, err error) {
+	if !cmd.Flags().Changed("backends") && !cmd.Flags().Changed("tls") {
+		return nil, nil, errkind.Errorf(errkind.Validation, "nothing to update, set backends or tls")
+	}
+	if cmd.Flags().Changed("backends") {
+		backends, err := cmd.Flags().GetStringSlice("backends")
+		if err != nil {
+			return nil, nil, err
+		}
+		zosBackends = []zos.Backend{}
+		for _, backend := range backends {
+			zosBackends = append(zosBackends, zos.Backend(backend))
+		}
+	}
+	if cmd.Flags().Changed("tls") {
+		value, err := cmd.Flags().GetBool("tls")
+		if err != nil {
+			return nil, nil, err
+		}
+		tls = &value
+	}
+	return zosBackends, tls, nil
+}
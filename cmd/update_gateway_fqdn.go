@@ -0,0 +1,43 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// updateGatewayFQDNCmd represents the update gateway fqdn command
+var updateGatewayFQDNCmd = &cobra.Command{
+	Use:   "fqdn",
+	Short: "Update a gateway FQDN proxy",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		backends, tls, err := parseUpdateGatewayFlags(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		gateway, err := command.UpdateGatewayFQDN(ctx, t, args[0], backends, tls)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("backends: %v", gateway.Backends)
+		log.Info().Msg("gateway fqdn updated")
+		return nil
+	},
+}
+
+func init() {
+	updateGatewayCmd.AddCommand(updateGatewayFQDNCmd)
+}
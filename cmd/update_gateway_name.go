@@ -0,0 +1,43 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+)
+
+// updateGatewayNameCmd represents the update gateway name command
+var updateGatewayNameCmd = &cobra.Command{
+	Use:   "name",
+	Short: "Update a gateway name proxy",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		backends, tls, err := parseUpdateGatewayFlags(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		gateway, err := command.UpdateGatewayName(ctx, t, args[0], backends, tls)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("backends: %v", gateway.Backends)
+		log.Info().Msgf("fqdn: %s", gateway.FQDN)
+		return nil
+	},
+}
+
+func init() {
+	updateGatewayCmd.AddCommand(updateGatewayNameCmd)
+}
@@ -0,0 +1,68 @@
+// Package cmd for parsing command line arguments
+package cmd
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	command "github.com/threefoldtech/tf-grid-cli/internal/cmd"
+	"github.com/threefoldtech/tf-grid-cli/internal/config"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+// updateVMCmd represents the update vm command
+var updateVMCmd = &cobra.Command{
+	Use:   "vm",
+	Short: "Resize a deployed vm",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cpu, err := cmd.Flags().GetInt("cpu")
+		if err != nil {
+			return err
+		}
+		memory, err := cmd.Flags().GetInt("memory")
+		if err != nil {
+			return err
+		}
+		diskSize, err := cmd.Flags().GetInt("disk-size")
+		if err != nil {
+			return err
+		}
+		if cpu == 0 && memory == 0 && diskSize == 0 {
+			return errkind.Errorf(errkind.Validation, "nothing to update, set cpu, memory or disk-size")
+		}
+		cfg, err := config.GetUserConfig()
+		if err != nil {
+			return err
+		}
+		t, err := newTFPluginClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		vm, err := command.ResizeVM(ctx, t, args[0], cpu, memory*1024, diskSize)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("vm %s resized to %d cpu and %d gb memory", vm.Name, vm.CPU, vm.Memory/1024)
+		if vm.ComputedIP != "" {
+			log.Info().Msgf("vm ipv4: %s", vm.ComputedIP)
+		}
+		if vm.ComputedIP6 != "" {
+			log.Info().Msgf("vm ipv6: %s", vm.ComputedIP6)
+		}
+		if vm.YggIP != "" {
+			log.Info().Msgf("vm yggdrasil ip: %s", vm.YggIP)
+		}
+		log.Info().Msgf("vm private ip: %s", vm.IP)
+		return nil
+	},
+}
+
+func init() {
+	updateCmd.AddCommand(updateVMCmd)
+
+	updateVMCmd.Flags().Int("cpu", 0, "new number of cpu units")
+	updateVMCmd.Flags().Int("memory", 0, "new memory size in gb")
+	updateVMCmd.Flags().Int("disk-size", 0, "new size in gb of the disk mounted on /data, it can only grow")
+}
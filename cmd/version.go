@@ -15,9 +15,10 @@ var version string
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Get latest build tag",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println(version)
 		fmt.Println(commit)
+		return nil
 	},
 }
 
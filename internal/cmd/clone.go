@@ -0,0 +1,179 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/grid_proxy_server/pkg/types"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/tf-grid-cli/internal/filters"
+)
+
+// CloneProject clones a vm or kubernetes project with CloneVM or CloneK8sCluster, it returns the project type
+// and the wg-quick config to access the cloned vm network if it has wireguard access.
+// other deployments of the project, like gateways and zdbs, are not cloned and are listed in a warning
+func CloneProject(ctx context.Context, t, target deployer.TFPluginClient, name, newName string) (string, string, error) {
+	deploymentType, err := getProjectType(t, name)
+	if err != nil {
+		return "", "", err
+	}
+	contracts, _, err := listProjectContracts(t, name)
+	if err != nil {
+		return "", "", err
+	}
+	if skipped := skippedCloneParts(contracts, name, deploymentType); len(skipped) != 0 {
+		log.Warn().Msgf("not cloning %s of project %s, only its vm or kubernetes cluster is cloned", strings.Join(skipped, ", "), name)
+	}
+	if deploymentType == "kubernetes" {
+		_, err = CloneK8sCluster(ctx, t, target, name, newName)
+		return deploymentType, "", err
+	}
+	_, wgConfig, err := CloneVM(ctx, t, target, name, newName)
+	return deploymentType, wgConfig, err
+}
+
+// CloneVM deploys a vm project read with t as newName with target, which may use another account or network.
+// the vm is deployed on the same node if it is up on the target network, or on a node picked by the node filters.
+// disk contents are not copied
+func CloneVM(ctx context.Context, t, target deployer.TFPluginClient, name, newName string) (workloads.VM, string, error) {
+	if err := checkCloneName(target, newName); err != nil {
+		return workloads.VM{}, "", err
+	}
+	dl, err := GetVM(t, name)
+	if err != nil {
+		return workloads.VM{}, "", err
+	}
+	if len(dl.QSFS) != 0 {
+		return workloads.VM{}, "", errkind.Errorf(errkind.Validation, "vm %s uses qsfs, which can't be cloned", name)
+	}
+	if len(dl.Disks) > 1 {
+		return workloads.VM{}, "", errkind.Errorf(errkind.Validation, "vm %s has %d disks, only vms with one disk can be cloned", name, len(dl.Disks))
+	}
+	vm, err := getVMWorkload(t, name)
+	if err != nil {
+		return workloads.VM{}, "", err
+	}
+	mount := workloads.Disk{}
+	if len(dl.Disks) != 0 {
+		mount = dl.Disks[0]
+	}
+	network, err := LoadNetwork(ctx, t, dl.NetworkName)
+	if err != nil {
+		return workloads.VM{}, "", err
+	}
+
+	node, err := cloneNode(ctx, target, dl.NodeID, filters.BuildVMFilter(vm, mount, 0))
+	if err != nil {
+		return workloads.VM{}, "", err
+	}
+	vm.Name = newName
+	vm.IP, vm.ComputedIP, vm.ComputedIP6, vm.YggIP = "", "", "", ""
+	log.Info().Msgf("cloning vm %s as %s on node %d", name, newName, node)
+	return DeployVM(ctx, target, vm, mount, workloads.QSFS{}, node, network.IPRange, network.AddWGAccess)
+}
+
+// CloneK8sCluster deploys a kubernetes cluster read with t as newName with target, which may use another account or network.
+// the cluster nodes are deployed on the same nodes if they are up on the target network, or on nodes picked by the node filters.
+// disk contents are not copied
+func CloneK8sCluster(ctx context.Context, t, target deployer.TFPluginClient, name, newName string) (workloads.K8sCluster, error) {
+	if err := checkCloneName(target, newName); err != nil {
+		return workloads.K8sCluster{}, err
+	}
+	cluster, err := GetK8sCluster(t, name)
+	if err != nil {
+		return workloads.K8sCluster{}, err
+	}
+	sshKey, err := getZMachineEnv(t, cluster.Master.Node, cluster.Master.Name, name, "SSH_KEY")
+	if err != nil {
+		return workloads.K8sCluster{}, err
+	}
+	network, err := LoadNetwork(ctx, t, cluster.NetworkName)
+	if err != nil {
+		return workloads.K8sCluster{}, err
+	}
+
+	// k8s nodes sharing a node are kept together on the target network
+	k8sNodesCount := map[uint32]uint{cluster.Master.Node: 1}
+	for _, worker := range cluster.Workers {
+		k8sNodesCount[worker.Node]++
+	}
+	nodes := map[uint32]uint32{}
+	cloneK8sNode := func(k8sNode workloads.K8sNode) (workloads.K8sNode, error) {
+		node, ok := nodes[k8sNode.Node]
+		if !ok {
+			node, err = cloneNode(ctx, target, k8sNode.Node, filters.BuildK8sFilter(k8sNode, 0, k8sNodesCount[k8sNode.Node]))
+			if err != nil {
+				return workloads.K8sNode{}, err
+			}
+			nodes[k8sNode.Node] = node
+		}
+		k8sNode.Node = node
+		k8sNode.IP, k8sNode.ComputedIP, k8sNode.ComputedIP6, k8sNode.YggIP = "", "", "", ""
+		return k8sNode, nil
+	}
+
+	master, err := cloneK8sNode(*cluster.Master)
+	if err != nil {
+		return workloads.K8sCluster{}, err
+	}
+	master.Name = newName
+	workers := make([]workloads.K8sNode, 0, len(cluster.Workers))
+	for _, worker := range cluster.Workers {
+		worker, err := cloneK8sNode(worker)
+		if err != nil {
+			return workloads.K8sCluster{}, err
+		}
+		workers = append(workers, worker)
+	}
+	log.Info().Msgf("cloning kubernetes cluster %s as %s", name, newName)
+	return DeployKubernetesCluster(ctx, target, master, workers, sshKey, network.IPRange)
+}
+
+// checkCloneName checks no project named name is deployed with target
+func checkCloneName(target deployer.TFPluginClient, name string) error {
+	contracts, err := target.ContractsGetter.ListContractsOfProjectName(name)
+	if err != nil {
+		return err
+	}
+	if len(contracts.NodeContracts) != 0 || len(contracts.NameContracts) != 0 {
+		return errkind.Errorf(errkind.Validation, "a project with name %s is already deployed on the target network, use another name", name)
+	}
+	return nil
+}
+
+// cloneNode returns node if it matches filter on the target network, with enough free resources and public ips,
+// or a node of any farm matching filter
+func cloneNode(ctx context.Context, target deployer.TFPluginClient, node uint32, filter types.NodeFilter) (uint32, error) {
+	checker := NodeChecker(target)
+	filter.FarmIDs = nil
+	sameNode := filter
+	nodeID := uint64(node)
+	sameNode.NodeID = &nodeID
+	nodes, _, err := target.GridProxyClient.Nodes(sameNode, types.Limit{})
+	if err == nil && len(nodes) != 0 && (checker == nil || filters.CheckNode(ctx, node, checker) == nil) {
+		return node, nil
+	}
+	log.Info().Msgf("node %d is not available on the target network or has not enough free resources, picking another node", node)
+	return filters.GetAvailableNode(ctx, target.GridProxyClient, filter, checker)
+}
+
+// skippedCloneParts describes the deployments of a project that are not cloned with its vm or kubernetes cluster
+func skippedCloneParts(contracts []projectContract, projectName, deploymentType string) []string {
+	var skipped []string
+	for _, contract := range contracts {
+		if contract.deploymentType == "network" || (contract.deploymentType == deploymentType && contract.name == projectName) {
+			continue
+		}
+		kind := "deployment"
+		if contract.deploymentType == "Gateway Name" || contract.deploymentType == "Gateway Fqdn" {
+			kind = strings.ToLower(contract.deploymentType)
+		}
+		skipped = append(skipped, fmt.Sprintf("%s %s on node %d", kind, contract.name, contract.nodeID))
+	}
+	return skipped
+}
@@ -0,0 +1,66 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	proxy "github.com/threefoldtech/grid_proxy_server/pkg/client"
+	"github.com/threefoldtech/grid_proxy_server/pkg/types"
+	"github.com/threefoldtech/tf-grid-cli/internal/filters"
+)
+
+// fakeProxy returns the nodes with enough free memory, only the given node if the filter has a node id
+type fakeProxy struct {
+	proxy.Client
+	nodes []fakeNode
+}
+
+type fakeNode struct {
+	id      int
+	freeMRU uint64
+}
+
+func (p fakeProxy) Nodes(filter types.NodeFilter, pagination types.Limit) ([]types.Node, int, error) {
+	var res []types.Node
+	for _, node := range p.nodes {
+		if filter.NodeID != nil && uint64(node.id) != *filter.NodeID {
+			continue
+		}
+		if node.freeMRU < *filter.FreeMRU {
+			continue
+		}
+		res = append(res, types.Node{NodeID: node.id})
+	}
+	return res, len(res), nil
+}
+
+func TestCloneNode(t *testing.T) {
+	defer func(check bool) { CheckNodes = check }(CheckNodes)
+	CheckNodes = false
+
+	target := deployer.TFPluginClient{GridProxyClient: fakeProxy{nodes: []fakeNode{{id: 11, freeMRU: 1}, {id: 12, freeMRU: 8}}}}
+	filter := filters.BuildVMFilter(workloads.VM{Memory: 2048}, workloads.Disk{}, 0)
+
+	node, err := cloneNode(context.Background(), target, 12, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(12), node)
+
+	node, err = cloneNode(context.Background(), target, 11, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(12), node)
+}
+
+func TestSkippedCloneParts(t *testing.T) {
+	contracts := []projectContract{
+		{name: "vm1", deploymentType: "vm", nodeID: 11},
+		{name: "vm1network", deploymentType: "network", nodeID: 11},
+		{name: "site", deploymentType: "Gateway Name", nodeID: 14},
+		{name: "vm1zdb", deploymentType: "vm", nodeID: 12},
+	}
+	assert.Equal(t, []string{"gateway name site on node 14", "deployment vm1zdb on node 12"}, skippedCloneParts(contracts, "vm1", "vm"))
+	assert.Nil(t, skippedCloneParts(contracts[:2], "vm1", "vm"))
+}
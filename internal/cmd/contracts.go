@@ -0,0 +1,154 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/subi"
+	"github.com/threefoldtech/substrate-client"
+)
+
+// ContractTracker records the node and name contracts created through a grid client,
+// so they can be rolled back if the command creating them is interrupted
+type ContractTracker struct {
+	subi.SubstrateExt
+	identity substrate.Identity
+
+	mu      sync.Mutex
+	created map[uint64]bool
+}
+
+// TrackContracts returns a copy of a grid client recording the contracts it creates in the returned tracker
+func TrackContracts(t deployer.TFPluginClient) (deployer.TFPluginClient, *ContractTracker) {
+	tracker := &ContractTracker{
+		SubstrateExt: t.SubstrateConn,
+		identity:     t.Identity,
+		created:      map[uint64]bool{},
+	}
+	t.SubstrateConn = tracker
+	rebuildDeployers(&t)
+	return t, tracker
+}
+
+// rebuildDeployers recreates the deployers and state of a grid client after its connections are replaced,
+// as they keep the connections they are created with
+func rebuildDeployers(t *deployer.TFPluginClient) {
+	t.DeploymentDeployer = deployer.NewDeploymentDeployer(t)
+	t.NetworkDeployer = deployer.NewNetworkDeployer(t)
+	t.GatewayFQDNDeployer = deployer.NewGatewayFqdnDeployer(t)
+	t.K8sDeployer = deployer.NewK8sDeployer(t)
+	t.GatewayNameDeployer = deployer.NewGatewayNameDeployer(t)
+	t.State = deployer.NewState(t.NcPool, t.SubstrateConn)
+}
+
+// CreateNodeContract creates a node contract and records it
+func (c *ContractTracker) CreateNodeContract(identity substrate.Identity, node uint32, body string, hash string, publicIPs uint32, solutionProviderID *uint64) (uint64, error) {
+	contractID, err := c.SubstrateExt.CreateNodeContract(identity, node, body, hash, publicIPs, solutionProviderID)
+	if err == nil {
+		c.add(contractID)
+	}
+	return contractID, err
+}
+
+// CreateNameContract creates a name contract and records it
+func (c *ContractTracker) CreateNameContract(identity substrate.Identity, name string) (uint64, error) {
+	contractID, err := c.SubstrateExt.CreateNameContract(identity, name)
+	if err == nil {
+		c.add(contractID)
+	}
+	return contractID, err
+}
+
+// CancelContract cancels a contract and forgets it
+func (c *ContractTracker) CancelContract(identity substrate.Identity, contractID uint64) error {
+	err := c.SubstrateExt.CancelContract(identity, contractID)
+	if err == nil {
+		c.remove(contractID)
+	}
+	return err
+}
+
+// EnsureContractCanceled cancels a contract if it exists and forgets it
+func (c *ContractTracker) EnsureContractCanceled(identity substrate.Identity, contractID uint64) error {
+	err := c.SubstrateExt.EnsureContractCanceled(identity, contractID)
+	if err == nil {
+		c.remove(contractID)
+	}
+	return err
+}
+
+// Created returns the recorded contracts that were not canceled, sorted
+func (c *ContractTracker) Created() []uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	contracts := make([]uint64, 0, len(c.created))
+	for contractID := range c.created {
+		contracts = append(contracts, contractID)
+	}
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i] < contracts[j] })
+	return contracts
+}
+
+// Rollback cancels the recorded contracts, newest first, and returns the ones that could not be canceled
+func (c *ContractTracker) Rollback() []uint64 {
+	created := c.Created()
+	var remaining []uint64
+	for i := len(created) - 1; i >= 0; i-- {
+		log.Info().Msgf("canceling contract %d", created[i])
+		if err := c.EnsureContractCanceled(c.identity, created[i]); err != nil {
+			log.Error().Err(err).Msgf("could not cancel contract %d", created[i])
+			remaining = append(remaining, created[i])
+		}
+	}
+	return remaining
+}
+
+// Commit forgets the recorded contracts, so they are kept if the command is interrupted afterwards
+func (c *ContractTracker) Commit() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.created = map[uint64]bool{}
+}
+
+// CommitContracts keeps the contracts created so far through a grid client if the command is interrupted afterwards,
+// once a step that can't be undone by canceling them starts
+func CommitContracts(t deployer.TFPluginClient) {
+	if tracker, ok := t.SubstrateConn.(*ContractTracker); ok {
+		tracker.Commit()
+	}
+}
+
+// uninterruptible keeps the values of a context but is never canceled, for steps that must not stop halfway
+// like removing workloads then adding them back
+type uninterruptible struct {
+	context.Context
+}
+
+func (uninterruptible) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (uninterruptible) Done() <-chan struct{} {
+	return nil
+}
+
+func (uninterruptible) Err() error {
+	return nil
+}
+
+func (c *ContractTracker) add(contractID uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.created[contractID] = true
+}
+
+func (c *ContractTracker) remove(contractID uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.created, contractID)
+}
@@ -0,0 +1,87 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/grid3-go/subi"
+	"github.com/threefoldtech/substrate-client"
+)
+
+type fakeSubstrate struct {
+	subi.SubstrateExt
+	nextID   uint64
+	failOn   uint64
+	canceled []uint64
+}
+
+func (f *fakeSubstrate) CreateNodeContract(identity substrate.Identity, node uint32, body string, hash string, publicIPs uint32, solutionProviderID *uint64) (uint64, error) {
+	f.nextID++
+	return f.nextID, nil
+}
+
+func (f *fakeSubstrate) CreateNameContract(identity substrate.Identity, name string) (uint64, error) {
+	f.nextID++
+	return f.nextID, nil
+}
+
+func (f *fakeSubstrate) CancelContract(identity substrate.Identity, contractID uint64) error {
+	return f.EnsureContractCanceled(identity, contractID)
+}
+
+func (f *fakeSubstrate) EnsureContractCanceled(identity substrate.Identity, contractID uint64) error {
+	if contractID == f.failOn {
+		return errors.New("failed to cancel")
+	}
+	f.canceled = append(f.canceled, contractID)
+	return nil
+}
+
+func TestContractTracker(t *testing.T) {
+	sub := &fakeSubstrate{failOn: 2}
+	tracker := &ContractTracker{SubstrateExt: sub, created: map[uint64]bool{}}
+
+	for i := 0; i < 3; i++ {
+		_, err := tracker.CreateNodeContract(nil, 1, "", "", 0, nil)
+		assert.NoError(t, err)
+	}
+	_, err := tracker.CreateNameContract(nil, "name")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3, 4}, tracker.Created())
+
+	assert.NoError(t, tracker.CancelContract(nil, 3))
+	assert.Equal(t, []uint64{1, 2, 4}, tracker.Created())
+
+	remaining := tracker.Rollback()
+	assert.Equal(t, []uint64{2}, remaining)
+	assert.Equal(t, []uint64{3, 4, 1}, sub.canceled)
+	assert.Equal(t, []uint64{2}, tracker.Created())
+}
+
+func TestContractTrackerCommit(t *testing.T) {
+	sub := &fakeSubstrate{}
+	tracker := &ContractTracker{SubstrateExt: sub, created: map[uint64]bool{}}
+
+	_, err := tracker.CreateNodeContract(nil, 1, "", "", 0, nil)
+	assert.NoError(t, err)
+	tracker.Commit()
+	_, err = tracker.CreateNodeContract(nil, 1, "", "", 0, nil)
+	assert.NoError(t, err)
+
+	assert.Empty(t, tracker.Rollback())
+	assert.Equal(t, []uint64{2}, sub.canceled)
+}
+
+func TestUninterruptible(t *testing.T) {
+	type key struct{}
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+	cancel()
+
+	ctx := uninterruptible{parent}
+	assert.NoError(t, ctx.Err())
+	assert.Nil(t, ctx.Done())
+	assert.Equal(t, "value", ctx.Value(key{}))
+}
@@ -3,54 +3,154 @@ package cmd
 
 import (
 	"context"
-	"fmt"
 	"net"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/threefoldtech/grid3-go/deployer"
 	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
 	"github.com/threefoldtech/zos/pkg/gridtypes"
 )
 
 // DeployVM deploys a vm with mounts
-func DeployVM(t deployer.TFPluginClient, vm workloads.VM, mount workloads.Disk, node uint32) (workloads.VM, error) {
-	networkName := fmt.Sprintf("%snetwork", vm.Name)
-	network := buildNetwork(networkName, vm.Name, []uint32{node})
+// if wireguard is set, it also returns the wg-quick config to access the vm network
+func DeployVM(ctx context.Context, t deployer.TFPluginClient, vm workloads.VM, mount workloads.Disk, qsfs workloads.QSFS, node uint32, networkRange gridtypes.IPNet, wireguard bool) (workloads.VM, string, error) {
+	networkName := projectNetworkName(vm.Name)
+	network := buildNetwork(networkName, vm.Name, []uint32{node}, networkRange)
+	if vm.IP != "" {
+		if err := validateNetworkIP(network.IPRange, vm.IP); err != nil {
+			return workloads.VM{}, "", err
+		}
+	}
+	if wireguard {
+		if err := EnableWGAccess(ctx, t, &network); err != nil {
+			return workloads.VM{}, "", err
+		}
+	}
 
 	mounts := []workloads.Disk{}
 	if mount.SizeGB != 0 {
 		mounts = append(mounts, mount)
 	}
+	qsfss := []workloads.QSFS{}
+	if qsfs.Name != "" {
+		qsfss = append(qsfss, qsfs)
+	}
 	vm.NetworkName = networkName
-	dl := workloads.NewDeployment(vm.Name, node, vm.Name, nil, networkName, mounts, nil, []workloads.VM{vm}, nil)
+	dl := workloads.NewDeployment(vm.Name, node, vm.Name, nil, networkName, mounts, nil, []workloads.VM{vm}, qsfss)
 
 	log.Info().Msg("deploying network")
-	err := t.NetworkDeployer.Deploy(context.Background(), &network)
+	err := t.NetworkDeployer.Deploy(ctx, &network)
 	if err != nil {
-		return workloads.VM{}, errors.Wrapf(err, "failed to deploy network on node %d", node)
+		return workloads.VM{}, "", errors.Wrapf(err, "failed to deploy network on node %d", node)
+	}
+	// the node subnet is only known once the network is deployed
+	if vm.IP != "" {
+		if err := validateNodeIP(network, node, vm.IP); err != nil {
+			return workloads.VM{}, "", cancelNetwork(ctx, t, &network, err)
+		}
 	}
 	log.Info().Msg("deploying vm")
-	err = t.DeploymentDeployer.Deploy(context.Background(), &dl)
+	err = t.DeploymentDeployer.Deploy(ctx, &dl)
 	if err != nil {
-		return workloads.VM{}, errors.Wrapf(err, "failed to deploy vm on node %d", node)
+		return workloads.VM{}, "", errors.Wrapf(err, "failed to deploy vm on node %d", node)
 	}
 	resVM, err := t.State.LoadVMFromGrid(node, vm.Name, dl.Name)
 	if err != nil {
-		return workloads.VM{}, errors.Wrapf(err, "failed to load vm from node %d", node)
+		return workloads.VM{}, "", errors.Wrapf(err, "failed to load vm from node %d", node)
 	}
-	return resVM, nil
+	return resVM, network.AccessWGConfig, nil
+}
+
+// DeployVMInNetwork deploys a vm with mounts into an existing network, extending it to the vm node if needed
+// if the network has wireguard access and is extended, the returned network holds a new wg-quick config
+func DeployVMInNetwork(ctx context.Context, t deployer.TFPluginClient, vm workloads.VM, mount workloads.Disk, qsfs workloads.QSFS, node uint32, networkName string) (workloads.VM, workloads.ZNet, error) {
+	network, err := LoadNetwork(ctx, t, networkName)
+	if err != nil {
+		return workloads.VM{}, workloads.ZNet{}, err
+	}
+	if vm.IP != "" {
+		if err := validateNetworkIP(network.IPRange, vm.IP); err != nil {
+			return workloads.VM{}, workloads.ZNet{}, err
+		}
+	}
+	extended := !workloads.Contains(network.Nodes, node)
+	if extended {
+		network.Nodes = append(network.Nodes, node)
+		if err := updateNetwork(ctx, t, &network); err != nil {
+			return workloads.VM{}, workloads.ZNet{}, err
+		}
+	}
+	if vm.IP != "" {
+		if err := validateNodeIP(network, node, vm.IP); err != nil {
+			return workloads.VM{}, workloads.ZNet{}, removeExtendedNode(ctx, t, extended, networkName, node, err)
+		}
+	}
+
+	// reserve ips of workloads already in the network so the vm gets a free one
+	members, err := reserveNetworkIPs(ctx, t, network, []uint32{node})
+	if err != nil {
+		return workloads.VM{}, workloads.ZNet{}, removeExtendedNode(ctx, t, extended, networkName, node, err)
+	}
+	for _, member := range members {
+		if member.NodeID == node && vm.IP == member.IP {
+			err := errkind.Errorf(errkind.Validation, "ip %s is already used by %s in network %s", vm.IP, member.Name, networkName)
+			return workloads.VM{}, workloads.ZNet{}, removeExtendedNode(ctx, t, extended, networkName, node, err)
+		}
+	}
+
+	mounts := []workloads.Disk{}
+	if mount.SizeGB != 0 {
+		mounts = append(mounts, mount)
+	}
+	qsfss := []workloads.QSFS{}
+	if qsfs.Name != "" {
+		qsfss = append(qsfss, qsfs)
+	}
+	vm.NetworkName = networkName
+	dl := workloads.NewDeployment(vm.Name, node, vm.Name, nil, networkName, mounts, nil, []workloads.VM{vm}, qsfss)
+
+	log.Info().Msg("deploying vm")
+	err = t.DeploymentDeployer.Deploy(ctx, &dl)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to deploy vm on node %d", node)
+		return workloads.VM{}, workloads.ZNet{}, removeExtendedNode(ctx, t, extended, networkName, node, err)
+	}
+	resVM, err := t.State.LoadVMFromGrid(node, vm.Name, dl.Name)
+	if err != nil {
+		return workloads.VM{}, workloads.ZNet{}, errors.Wrapf(err, "failed to load vm from node %d", node)
+	}
+	return resVM, network, nil
+}
+
+// removeExtendedNode removes the node a network was extended to for a vm that could not be deployed, then returns err.
+// it is not stopped by interrupts, and err is returned as a partial failure if the node is kept in the network
+func removeExtendedNode(ctx context.Context, t deployer.TFPluginClient, extended bool, networkName string, node uint32, err error) error {
+	if !extended {
+		return err
+	}
+	log.Info().Msgf("removing node %d from network %s", node, networkName)
+	if _, rerr := RemoveNetworkNode(uninterruptible{ctx}, t, networkName, node); rerr != nil {
+		return errkind.Wrap(errkind.PartialFailure, errors.Wrapf(err, "failed to remove node %d from network %s: %s", node, networkName, rerr))
+	}
+	return err
 }
 
 // DeployKubernetesCluster deploys a kubernetes cluster
-func DeployKubernetesCluster(t deployer.TFPluginClient, master workloads.K8sNode, workers []workloads.K8sNode, sshKey string) (workloads.K8sCluster, error) {
+func DeployKubernetesCluster(ctx context.Context, t deployer.TFPluginClient, master workloads.K8sNode, workers []workloads.K8sNode, sshKey string, networkRange gridtypes.IPNet) (workloads.K8sCluster, error) {
 
-	networkName := fmt.Sprintf("%snetwork", master.Name)
+	networkName := projectNetworkName(master.Name)
 	networkNodes := []uint32{master.Node}
 	if len(workers) > 0 && workers[0].Node != master.Node {
 		networkNodes = append(networkNodes, workers[0].Node)
 	}
-	network := buildNetwork(networkName, master.Name, networkNodes)
+	network := buildNetwork(networkName, master.Name, networkNodes, networkRange)
+	if master.IP != "" {
+		if err := validateNetworkIP(network.IPRange, master.IP); err != nil {
+			return workloads.K8sCluster{}, err
+		}
+	}
 
 	cluster := workloads.K8sCluster{
 		Master:  &master,
@@ -62,12 +162,18 @@ func DeployKubernetesCluster(t deployer.TFPluginClient, master workloads.K8sNode
 		NetworkName:  networkName,
 	}
 	log.Info().Msg("deploying network")
-	err := t.NetworkDeployer.Deploy(context.Background(), &network)
+	err := t.NetworkDeployer.Deploy(ctx, &network)
 	if err != nil {
 		return workloads.K8sCluster{}, errors.Wrapf(err, "failed to deploy network on nodes %v", network.Nodes)
 	}
+	// the node subnet is only known once the network is deployed
+	if master.IP != "" {
+		if err := validateNodeIP(network, master.Node, master.IP); err != nil {
+			return workloads.K8sCluster{}, cancelNetwork(ctx, t, &network, err)
+		}
+	}
 	log.Info().Msg("deploying cluster")
-	err = t.K8sDeployer.Deploy(context.Background(), &cluster)
+	err = t.K8sDeployer.Deploy(ctx, &cluster)
 	if err != nil {
 		return workloads.K8sCluster{}, errors.Wrap(err, "failed to deploy kubernetes cluster")
 	}
@@ -81,10 +187,22 @@ func DeployKubernetesCluster(t deployer.TFPluginClient, master workloads.K8sNode
 	)
 }
 
+// cancelNetwork cancels the network of a project that failed to deploy, and returns the error it failed with
+func cancelNetwork(ctx context.Context, t deployer.TFPluginClient, network *workloads.ZNet, err error) error {
+	log.Info().Msgf("canceling network %s", network.Name)
+	if cerr := t.NetworkDeployer.Cancel(ctx, network); cerr != nil {
+		return errkind.Wrap(errkind.PartialFailure, errors.Wrapf(err, "failed to cancel network %s, cancel its contracts manually: %s", network.Name, cerr))
+	}
+	return err
+}
+
 // DeployGatewayName deploys a gateway name
-func DeployGatewayName(t deployer.TFPluginClient, gateway workloads.GatewayNameProxy) (workloads.GatewayNameProxy, error) {
+func DeployGatewayName(ctx context.Context, t deployer.TFPluginClient, gateway workloads.GatewayNameProxy) (workloads.GatewayNameProxy, error) {
+	if err := CheckGatewayName(t, gateway.Name); err != nil {
+		return workloads.GatewayNameProxy{}, err
+	}
 	log.Info().Msg("deploying gateway name")
-	err := t.GatewayNameDeployer.Deploy(context.Background(), &gateway)
+	err := t.GatewayNameDeployer.Deploy(ctx, &gateway)
 	if err != nil {
 		return workloads.GatewayNameProxy{}, errors.Wrapf(err, "failed to deploy gateway on node %d", gateway.NodeID)
 	}
@@ -92,24 +210,57 @@ func DeployGatewayName(t deployer.TFPluginClient, gateway workloads.GatewayNameP
 }
 
 // DeployGatewayFQDN deploys a gateway fqdn
-func DeployGatewayFQDN(t deployer.TFPluginClient, gateway workloads.GatewayFQDNProxy) error {
+func DeployGatewayFQDN(ctx context.Context, t deployer.TFPluginClient, gateway workloads.GatewayFQDNProxy) error {
 
 	log.Info().Msg("deploying gateway fqdn")
-	err := t.GatewayFQDNDeployer.Deploy(context.Background(), &gateway)
+	err := t.GatewayFQDNDeployer.Deploy(ctx, &gateway)
 	if err != nil {
 		return errors.Wrapf(err, "failed to deploy gateway on node %d", gateway.NodeID)
 	}
 	return nil
 }
 
-func buildNetwork(name, projectName string, nodes []uint32) workloads.ZNet {
+// DeployZDB deploys a zdb
+func DeployZDB(ctx context.Context, t deployer.TFPluginClient, zdb workloads.ZDB, node uint32) (workloads.ZDB, error) {
+	dl := workloads.NewDeployment(zdb.Name, node, zdb.Name, nil, "", nil, []workloads.ZDB{zdb}, nil, nil)
+
+	log.Info().Msg("deploying zdb")
+	err := t.DeploymentDeployer.Deploy(ctx, &dl)
+	if err != nil {
+		return workloads.ZDB{}, errors.Wrapf(err, "failed to deploy zdb on node %d", node)
+	}
+	resZDB, err := t.State.LoadZdbFromGrid(node, zdb.Name, dl.Name)
+	if err != nil {
+		return workloads.ZDB{}, errors.Wrapf(err, "failed to load zdb from node %d", node)
+	}
+	return resZDB, nil
+}
+
+// reserveNetworkIPs loads the network subnets in the deployer state, and reserves the ips used by the network workloads on nodes
+// so workloads deployed there get free ips. it returns the network workloads
+func reserveNetworkIPs(ctx context.Context, t deployer.TFPluginClient, network workloads.ZNet, nodes []uint32) ([]NetworkWorkload, error) {
+	members, err := listNetworkWorkloads(ctx, t, network)
+	if err != nil {
+		return nil, err
+	}
+	t.State.GetNetworks().UpdateNetwork(network.Name, network.NodesIPRange)
+	networkState := t.State.GetNetworks().GetNetwork(network.Name)
+	for _, member := range members {
+		ip := net.ParseIP(member.IP).To4()
+		if !workloads.Contains(nodes, member.NodeID) || ip == nil {
+			continue
+		}
+		hostIDs := append(networkState.GetDeploymentHostIDs(member.NodeID, member.ContractID), ip[3])
+		networkState.SetDeploymentHostIDs(member.NodeID, member.ContractID, hostIDs)
+	}
+	return members, nil
+}
+
+func buildNetwork(name, projectName string, nodes []uint32, ipRange gridtypes.IPNet) workloads.ZNet {
 	return workloads.ZNet{
-		Name:  name,
-		Nodes: nodes,
-		IPRange: gridtypes.NewIPNet(net.IPNet{
-			IP:   net.IPv4(10, 20, 0, 0),
-			Mask: net.CIDRMask(16, 32),
-		}),
+		Name:         name,
+		Nodes:        nodes,
+		IPRange:      ipRange,
 		SolutionType: projectName,
 	}
 }
@@ -0,0 +1,84 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+// lookupIP resolves a domain with the system resolver
+var lookupIP = net.LookupIP
+
+// DNSRecord is a dns record needed to point a domain to a gateway node
+type DNSRecord struct {
+	Type  string
+	Value string
+}
+
+func (r DNSRecord) String() string {
+	return fmt.Sprintf("%s %s", r.Type, r.Value)
+}
+
+// GatewayDNSRecords returns the A and AAAA records an fqdn needs to point to a gateway node
+func GatewayDNSRecords(t deployer.TFPluginClient, node uint32) ([]DNSRecord, error) {
+	nodeInfo, err := t.GridProxyClient.Node(node)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get node %d data from the grid proxy", node)
+	}
+	records := []DNSRecord{}
+	if ip := configIP(nodeInfo.PublicConfig.Ipv4); ip != "" {
+		records = append(records, DNSRecord{Type: "A", Value: ip})
+	}
+	if ip := configIP(nodeInfo.PublicConfig.Ipv6); ip != "" {
+		records = append(records, DNSRecord{Type: "AAAA", Value: ip})
+	}
+	if len(records) == 0 {
+		return nil, errkind.Errorf(errkind.Validation, "node %d has no public ip to point a domain to", node)
+	}
+	return records, nil
+}
+
+// VerifyDNS checks an fqdn resolves only to the ips of the given records
+func VerifyDNS(fqdn string, records []DNSRecord) error {
+	ips, err := lookupIP(fqdn)
+	if err != nil {
+		return errors.Wrapf(err, "could not resolve %s", fqdn)
+	}
+	expected := map[string]bool{}
+	for _, record := range records {
+		expected[net.ParseIP(record.Value).String()] = true
+	}
+	for _, ip := range ips {
+		if !expected[ip.String()] {
+			return errkind.Errorf(errkind.Validation, "%s resolves to %s which is not the gateway node ip, expected records: %s", fqdn, ip, formatRecords(records))
+		}
+	}
+	return nil
+}
+
+// configIP returns the ip of a node public config ip, which may include its mask
+func configIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	if parsed, _, err := net.ParseCIDR(ip); err == nil {
+		return parsed.String()
+	}
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ""
+}
+
+func formatRecords(records []DNSRecord) string {
+	formatted := make([]string, 0, len(records))
+	for _, record := range records {
+		formatted = append(formatted, record.String())
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,42 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+func TestVerifyDNS(t *testing.T) {
+	records := []DNSRecord{{Type: "A", Value: "185.206.122.33"}, {Type: "AAAA", Value: "2a10:b600::1"}}
+	resolved := map[string][]net.IP{
+		"ok.example.com":      {net.ParseIP("185.206.122.33"), net.ParseIP("2a10:b600::1")},
+		"partial.example.com": {net.ParseIP("185.206.122.33")},
+		"wrong.example.com":   {net.ParseIP("185.206.122.33"), net.ParseIP("93.184.216.34")},
+	}
+	lookupIP = func(host string) ([]net.IP, error) {
+		ips, ok := resolved[host]
+		if !ok {
+			return nil, errors.New("no such host")
+		}
+		return ips, nil
+	}
+	defer func() { lookupIP = net.LookupIP }()
+
+	assert.NoError(t, VerifyDNS("ok.example.com", records))
+	assert.NoError(t, VerifyDNS("partial.example.com", records))
+	err := VerifyDNS("wrong.example.com", records)
+	assert.ErrorContains(t, err, "93.184.216.34")
+	assert.Equal(t, errkind.Validation, errkind.Of(err))
+	assert.Error(t, VerifyDNS("missing.example.com", records))
+}
+
+func TestConfigIP(t *testing.T) {
+	assert.Equal(t, "185.206.122.33", configIP("185.206.122.33/24"))
+	assert.Equal(t, "2a10:b600::1", configIP("2a10:b600::1/64"))
+	assert.Equal(t, "185.206.122.33", configIP("185.206.122.33"))
+	assert.Equal(t, "", configIP(""))
+}
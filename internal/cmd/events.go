@@ -0,0 +1,142 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+// eventsPollInterval is the time between polls of the nodes change history when following events
+var eventsPollInterval = 10 * time.Second
+
+// Event is a state transition of a workload of a project deployment, as recorded in its node change history
+type Event struct {
+	Time       time.Time `json:"time"`
+	Deployment string    `json:"deployment"`
+	NodeID     uint32    `json:"node_id"`
+	ContractID uint64    `json:"contract_id"`
+	Workload   string    `json:"workload"`
+	Type       string    `json:"type"`
+	Version    uint32    `json:"version"`
+	Event      string    `json:"event"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// String returns a timeline line of the event
+func (e Event) String() string {
+	line := fmt.Sprintf("%s %s %s/%s v%d on node %d: %s", e.Time.Format(time.RFC3339), e.Deployment, e.Type, e.Workload, e.Version, e.NodeID, e.Event)
+	if e.Error != "" {
+		line += ": " + e.Error
+	}
+	return line
+}
+
+// key identifies an event between polls of the change history
+func (e Event) key() string {
+	return fmt.Sprintf("%d/%s/%d/%s/%d", e.ContractID, e.Workload, e.Version, e.Event, e.Time.Unix())
+}
+
+// GetEvents gets the change history of every deployment of a project from its node, sorted by time
+func GetEvents(ctx context.Context, t deployer.TFPluginClient, projectName string) ([]Event, error) {
+	contracts, _, err := listProjectContracts(t, projectName)
+	if err != nil {
+		return nil, err
+	}
+	events := []Event{}
+	for _, contract := range contracts {
+		nodeClient, err := t.NcPool.GetNodeClient(t.SubstrateConn, contract.nodeID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get node %d client", contract.nodeID)
+		}
+		changes, err := nodeClient.DeploymentChanges(ctx, contract.contractID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get changes of deployment %d from node %d", contract.contractID, contract.nodeID)
+		}
+		events = append(events, deploymentEvents(contract, changes)...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events, nil
+}
+
+// FollowEvents polls the change history of a project and calls handle with every event not seen before
+// it only returns if handle fails or ctx is done, errors getting the history are logged and retried on the next poll
+func FollowEvents(ctx context.Context, t deployer.TFPluginClient, projectName string, handle func(Event) error) error {
+	seen := map[string]bool{}
+	for {
+		events, err := GetEvents(ctx, t, projectName)
+		if err != nil {
+			log.Warn().Err(err).Msg("could not get events, retrying")
+		}
+		for _, event := range newEvents(events, seen) {
+			if err := handle(event); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(eventsPollInterval):
+		}
+	}
+}
+
+// newEvents returns the events not in seen, and adds them to it
+func newEvents(events []Event, seen map[string]bool) []Event {
+	var added []Event
+	for _, event := range events {
+		if seen[event.key()] {
+			continue
+		}
+		seen[event.key()] = true
+		added = append(added, event)
+	}
+	return added
+}
+
+// deploymentEvents converts the change history of a deployment to events
+func deploymentEvents(contract projectContract, changes []gridtypes.Workload) []Event {
+	events := make([]Event, 0, len(changes))
+	lastVersions := map[gridtypes.Name]uint32{}
+	for _, change := range changes {
+		lastVersion, seen := lastVersions[change.Name]
+		events = append(events, Event{
+			Time:       time.Unix(int64(change.Result.Created), 0),
+			Deployment: contract.name,
+			NodeID:     contract.nodeID,
+			ContractID: contract.contractID,
+			Workload:   string(change.Name),
+			Type:       string(change.Type),
+			Version:    change.Version,
+			Event:      eventName(change, seen && change.Version > lastVersion),
+			Error:      change.Result.Error,
+		})
+		lastVersions[change.Name] = change.Version
+	}
+	return events
+}
+
+// eventName names the transition of a workload change: created, ok, error, updated, deleted
+func eventName(change gridtypes.Workload, versionBumped bool) string {
+	switch change.Result.State {
+	case gridtypes.StateInit:
+		if change.Version == 0 {
+			return "created"
+		}
+		return "updated"
+	case gridtypes.StateOk:
+		if versionBumped {
+			return "updated"
+		}
+		return "ok"
+	}
+	return string(change.Result.State)
+}
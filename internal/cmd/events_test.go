@@ -0,0 +1,57 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestDeploymentEvents(t *testing.T) {
+	change := func(version uint32, state gridtypes.ResultState, created gridtypes.Timestamp, msg string) gridtypes.Workload {
+		return gridtypes.Workload{
+			Name:    "vm",
+			Type:    zos.ZMachineType,
+			Version: version,
+			Result:  gridtypes.Result{State: state, Created: created, Error: msg},
+		}
+	}
+	contract := projectContract{name: "vm", deploymentType: "vm", nodeID: 11, contractID: 20}
+	changes := []gridtypes.Workload{
+		change(0, gridtypes.StateInit, 100, ""),
+		change(0, gridtypes.StateOk, 110, ""),
+		change(1, gridtypes.StateOk, 200, ""),
+		change(1, gridtypes.StateError, 300, "failed to start vm"),
+		change(1, gridtypes.StateDeleted, 400, ""),
+	}
+
+	events := deploymentEvents(contract, changes)
+	var names []string
+	for _, event := range events {
+		names = append(names, event.Event)
+	}
+	assert.Equal(t, []string{"created", "ok", "updated", "error", "deleted"}, names)
+	assert.Equal(t, int64(300), events[3].Time.Unix())
+	assert.Equal(t, "failed to start vm", events[3].Error)
+	assert.Equal(t, uint64(20), events[3].ContractID)
+}
+
+func TestNewEvents(t *testing.T) {
+	contract := projectContract{name: "vm", nodeID: 11, contractID: 20}
+	first := deploymentEvents(contract, []gridtypes.Workload{
+		{Name: "vm", Result: gridtypes.Result{State: gridtypes.StateInit, Created: 100}},
+	})
+	second := deploymentEvents(contract, []gridtypes.Workload{
+		{Name: "vm", Result: gridtypes.Result{State: gridtypes.StateInit, Created: 100}},
+		{Name: "vm", Result: gridtypes.Result{State: gridtypes.StateOk, Created: 110}},
+	})
+
+	seen := map[string]bool{}
+	assert.Len(t, newEvents(first, seen), 1)
+	added := newEvents(second, seen)
+	assert.Len(t, added, 1)
+	assert.Equal(t, "ok", added[0].Event)
+	assert.Empty(t, newEvents(second, seen))
+}
@@ -0,0 +1,54 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// ParseExpose parses an expose value in the form name:port
+func ParseExpose(expose string) (string, uint16, error) {
+	name, portStr, found := strings.Cut(expose, ":")
+	if !found || name == "" {
+		return "", 0, errkind.Errorf(errkind.Validation, "invalid expose %s, must be in the form name:port", expose)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return "", 0, errkind.Errorf(errkind.Validation, "invalid expose %s, port must be between 1 and 65535", expose)
+	}
+	return name, uint16(port), nil
+}
+
+// ExposeBackend returns the gateway backend of a service port, on the public ipv4 if set or the yggdrasil ip
+func ExposeBackend(computedIP, yggIP string, port uint16) (zos.Backend, error) {
+	if computedIP != "" {
+		ip, _, err := net.ParseCIDR(computedIP)
+		if err != nil {
+			return "", fmt.Errorf("invalid public ip %s", computedIP)
+		}
+		return zos.Backend(fmt.Sprintf("http://%s", net.JoinHostPort(ip.String(), strconv.Itoa(int(port))))), nil
+	}
+	if yggIP != "" {
+		return zos.Backend(fmt.Sprintf("http://%s", net.JoinHostPort(yggIP, strconv.Itoa(int(port))))), nil
+	}
+	return "", fmt.Errorf("no public or yggdrasil ip to expose port %d on", port)
+}
+
+// ExposeService deploys a gateway name in a project proxying to a service backend
+func ExposeService(ctx context.Context, t deployer.TFPluginClient, projectName, name string, node uint32, backend zos.Backend) (workloads.GatewayNameProxy, error) {
+	gateway := workloads.GatewayNameProxy{
+		NodeID:       node,
+		Name:         name,
+		Backends:     []zos.Backend{backend},
+		SolutionType: projectName,
+	}
+	return DeployGatewayName(ctx, t, gateway)
+}
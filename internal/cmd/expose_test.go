@@ -0,0 +1,34 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestParseExpose(t *testing.T) {
+	name, port, err := ParseExpose("webapp:8080")
+	assert.NoError(t, err)
+	assert.Equal(t, "webapp", name)
+	assert.Equal(t, uint16(8080), port)
+
+	for _, invalid := range []string{"webapp", ":8080", "webapp:", "webapp:0", "webapp:70000", "webapp:http"} {
+		_, _, err := ParseExpose(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestExposeBackend(t *testing.T) {
+	backend, err := ExposeBackend("185.206.122.33/24", "300:e9c4::1", 8080)
+	assert.NoError(t, err)
+	assert.Equal(t, zos.Backend("http://185.206.122.33:8080"), backend)
+
+	backend, err = ExposeBackend("", "300:e9c4::1", 80)
+	assert.NoError(t, err)
+	assert.Equal(t, zos.Backend("http://[300:e9c4::1]:80"), backend)
+
+	_, err = ExposeBackend("", "", 80)
+	assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid_proxy_server/pkg/types"
+	substrate "github.com/threefoldtech/substrate-client"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/tf-grid-cli/internal/filters"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// GatewayNode is a node serving a gateway domain
+// a name gateway deployed on it gets the fqdn <name>.<domain>
+type GatewayNode struct {
+	NodeID  int    `json:"node_id"`
+	FarmID  int    `json:"farm_id"`
+	Country string `json:"country"`
+	Domain  string `json:"domain"`
+	IPv4    string `json:"ipv4"`
+	IPv6    string `json:"ipv6"`
+}
+
+// ListGateways lists the up nodes with a gateway domain, farmID 0 and an empty country match any farm or country
+func ListGateways(t deployer.TFPluginClient, farmID uint64, country string) ([]GatewayNode, error) {
+	nodes, err := filters.GetNodes(t.GridProxyClient, filters.BuildGatewaysFilter(farmID, country))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list gateway nodes")
+	}
+	gateways := []GatewayNode{}
+	for _, node := range nodes {
+		if node.PublicConfig.Domain == "" {
+			continue
+		}
+		gateways = append(gateways, gatewayNode(node))
+	}
+	return gateways, nil
+}
+
+func gatewayNode(node types.Node) GatewayNode {
+	return GatewayNode{
+		NodeID:  node.NodeID,
+		FarmID:  node.FarmID,
+		Country: node.Country,
+		Domain:  node.PublicConfig.Domain,
+		IPv4:    configIP(node.PublicConfig.Ipv4),
+		IPv6:    configIP(node.PublicConfig.Ipv6),
+	}
+}
+
+// ValidateBackends validates gateway backends before deploying
+// backends must be http urls with an ip host, or ip:port if tls passthrough is enabled
+func ValidateBackends(backends []zos.Backend, tlsPassthrough bool) error {
+	if len(backends) == 0 {
+		return errkind.Errorf(errkind.Validation, "at least one backend is required")
+	}
+	for _, backend := range backends {
+		if err := validateBackend(string(backend), tlsPassthrough); err != nil {
+			return errors.Wrapf(err, "invalid backend %s", backend)
+		}
+	}
+	return nil
+}
+
+func validateBackend(backend string, tlsPassthrough bool) error {
+	if tlsPassthrough {
+		host, port, err := net.SplitHostPort(backend)
+		if err != nil {
+			return errkind.Errorf(errkind.Validation, "backends must be ip:port with tls passthrough")
+		}
+		if err := validateBackendIP(host); err != nil {
+			return err
+		}
+		return validateBackendPort(port)
+	}
+
+	u, err := url.Parse(backend)
+	if err != nil {
+		return errkind.Errorf(errkind.Validation, "backend must be a url like http://ip:port")
+	}
+	if u.Scheme != "http" {
+		return errkind.Errorf(errkind.Validation, "scheme must be http, got %q", u.Scheme)
+	}
+	if err := validateBackendIP(u.Hostname()); err != nil {
+		return err
+	}
+	if u.Port() != "" {
+		return validateBackendPort(u.Port())
+	}
+	return nil
+}
+
+func validateBackendIP(host string) error {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errkind.Errorf(errkind.Validation, "host must be an ip, got %q", host)
+	}
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return errkind.Errorf(errkind.Validation, "host %s is not reachable from the gateway", host)
+	}
+	return nil
+}
+
+func validateBackendPort(port string) error {
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || p == 0 {
+		return errkind.Errorf(errkind.Validation, "port must be between 1 and 65535, got %q", port)
+	}
+	return nil
+}
+
+// CheckGatewayName checks a gateway name is free to register before deploying
+func CheckGatewayName(t deployer.TFPluginClient, name string) error {
+	contractID, err := t.SubstrateConn.GetContractIDByNameRegistration(name)
+	if errors.Is(err, substrate.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return errkind.Wrap(errkind.Chain, errors.Wrapf(err, "could not check if gateway name %s is available", name))
+	}
+	contract, err := t.SubstrateConn.GetContract(contractID)
+	if err != nil {
+		return errkind.Wrap(errkind.Chain, errors.Wrapf(err, "could not get name contract %d of gateway name %s", contractID, name))
+	}
+	if contract.TwinID() == t.TwinID {
+		return errkind.Errorf(errkind.Validation, "gateway name %s is already registered by you in contract %d, update or cancel its deployment", name, contractID)
+	}
+	return errkind.Errorf(errkind.Validation, "gateway name %s is already taken", name)
+}
@@ -0,0 +1,27 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestValidateBackends(t *testing.T) {
+	t.Run("http backends", func(t *testing.T) {
+		assert.NoError(t, ValidateBackends([]zos.Backend{"http://93.184.216.34:80", "http://[300:e9c4::1]"}, false))
+		assert.Error(t, ValidateBackends(nil, false))
+		assert.ErrorContains(t, ValidateBackends([]zos.Backend{"https://93.184.216.34:443"}, false), "https://93.184.216.34:443")
+		assert.ErrorContains(t, ValidateBackends([]zos.Backend{"http://example.com"}, false), "host must be an ip")
+		assert.ErrorContains(t, ValidateBackends([]zos.Backend{"http://127.0.0.1:80"}, false), "not reachable")
+		assert.ErrorContains(t, ValidateBackends([]zos.Backend{"http://93.184.216.34:70000"}, false), "port")
+		assert.Error(t, ValidateBackends([]zos.Backend{"93.184.216.34:80"}, false))
+	})
+	t.Run("tls passthrough backends", func(t *testing.T) {
+		assert.NoError(t, ValidateBackends([]zos.Backend{"93.184.216.34:443", "[300:e9c4::1]:443"}, true))
+		assert.ErrorContains(t, ValidateBackends([]zos.Backend{"http://93.184.216.34:443"}, true), "ip:port")
+		assert.Error(t, ValidateBackends([]zos.Backend{"93.184.216.34"}, true))
+		assert.Error(t, ValidateBackends([]zos.Backend{"93.184.216.34:0"}, true))
+	})
+}
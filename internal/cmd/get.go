@@ -2,12 +2,13 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"strconv"
 
 	"github.com/threefoldtech/grid3-go/deployer"
 	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
 )
 
 // GetVM gets a vm with its project name
@@ -24,7 +25,7 @@ func GetVM(t deployer.TFPluginClient, name string) (workloads.Deployment, error)
 		if err != nil {
 			return workloads.Deployment{}, err
 		}
-		if deploymentData.Type != "vm" || deploymentData.ProjectName != name {
+		if deploymentData.Type != "vm" || deploymentData.Name != name {
 			continue
 		}
 		nodeID = contract.NodeID
@@ -37,7 +38,7 @@ func GetVM(t deployer.TFPluginClient, name string) (workloads.Deployment, error)
 		break
 	}
 	if nodeID == 0 {
-		return workloads.Deployment{}, fmt.Errorf("no vm with name %s found", name)
+		return workloads.Deployment{}, errkind.Errorf(errkind.NotFound, "no vm with name %s found", name)
 	}
 
 	return t.State.LoadDeploymentFromGrid(nodeID, name)
@@ -67,9 +68,14 @@ func GetK8sCluster(t deployer.TFPluginClient, name string) (workloads.K8sCluster
 		t.State.CurrentNodeDeployments[contract.NodeID] = append(t.State.CurrentNodeDeployments[contract.NodeID], contractID)
 	}
 	if nodeIDs == nil {
-		return workloads.K8sCluster{}, fmt.Errorf("no k8s cluster with name %s found", name)
+		return workloads.K8sCluster{}, errkind.Errorf(errkind.NotFound, "no k8s cluster with name %s found", name)
 	}
-	return t.State.LoadK8sFromGrid(nodeIDs, name)
+	cluster, err := t.State.LoadK8sFromGrid(nodeIDs, name)
+	if err != nil {
+		return workloads.K8sCluster{}, err
+	}
+	cluster.NetworkName = projectNetworkName(name)
+	return cluster, nil
 }
 
 // GetGatewayName gets a gateway name with its project name
@@ -99,7 +105,7 @@ func GetGatewayName(t deployer.TFPluginClient, name string) (workloads.GatewayNa
 		break
 	}
 	if nodeID == 0 {
-		return workloads.GatewayNameProxy{}, fmt.Errorf("no gateway name with name %s found", name)
+		return workloads.GatewayNameProxy{}, errkind.Errorf(errkind.NotFound, "no gateway name with name %s found", name)
 	}
 	return t.State.LoadGatewayNameFromGrid(nodeID, name, name)
 }
@@ -131,7 +137,52 @@ func GetGatewayFQDN(t deployer.TFPluginClient, name string) (workloads.GatewayFQ
 		break
 	}
 	if nodeID == 0 {
-		return workloads.GatewayFQDNProxy{}, fmt.Errorf("no gateway fqdn with name %s found", name)
+		return workloads.GatewayFQDNProxy{}, errkind.Errorf(errkind.NotFound, "no gateway fqdn with name %s found", name)
 	}
 	return t.State.LoadGatewayFQDNFromGrid(nodeID, name, name)
 }
+
+// GetZDB gets a zdb with its project name
+func GetZDB(t deployer.TFPluginClient, name string) (workloads.ZDB, error) {
+	contracts, err := t.ContractsGetter.ListContractsOfProjectName(name)
+	if err != nil {
+		return workloads.ZDB{}, err
+	}
+	var nodeID uint32
+	var contractID uint64
+	for _, contract := range contracts.NodeContracts {
+		var deploymentData workloads.DeploymentData
+		err := json.Unmarshal([]byte(contract.DeploymentData), &deploymentData)
+		if err != nil {
+			return workloads.ZDB{}, err
+		}
+		if deploymentData.Type != "vm" || deploymentData.Name != name {
+			continue
+		}
+		nodeID = contract.NodeID
+		contractID, err = strconv.ParseUint(contract.ContractID, 0, 64)
+		if err != nil {
+			return workloads.ZDB{}, err
+		}
+
+		t.State.CurrentNodeDeployments[nodeID] = []uint64{contractID}
+		break
+	}
+	if nodeID == 0 {
+		return workloads.ZDB{}, errkind.Errorf(errkind.NotFound, "no zdb with name %s found", name)
+	}
+	return t.State.LoadZdbFromGrid(nodeID, name, name)
+}
+
+// GetNetworkSubnets gets the subnet assigned to each node of a network
+func GetNetworkSubnets(ctx context.Context, t deployer.TFPluginClient, networkName string) (map[uint32]string, error) {
+	znet, err := LoadNetwork(ctx, t, networkName)
+	if err != nil {
+		return nil, err
+	}
+	subnets := make(map[uint32]string)
+	for node, subnet := range znet.NodesIPRange {
+		subnets[node] = subnet.String()
+	}
+	return subnets, nil
+}
@@ -0,0 +1,145 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	k3sConfigPath = "/etc/rancher/k3s/k3s.yaml"
+	k3sAPIPort    = "6443"
+)
+
+// KubeConfig is a kubectl config file
+type KubeConfig struct {
+	APIVersion     string                 `yaml:"apiVersion"`
+	Kind           string                 `yaml:"kind"`
+	Preferences    map[string]interface{} `yaml:"preferences,omitempty"`
+	Clusters       []KubeConfigEntry      `yaml:"clusters"`
+	Contexts       []KubeConfigEntry      `yaml:"contexts"`
+	Users          []KubeConfigEntry      `yaml:"users"`
+	CurrentContext string                 `yaml:"current-context"`
+	Extensions     interface{}            `yaml:"extensions,omitempty"`
+}
+
+// KubeConfigEntry is a named cluster, context or user of a kubectl config file
+type KubeConfigEntry struct {
+	Name    string                 `yaml:"name"`
+	Cluster map[string]interface{} `yaml:"cluster,omitempty"`
+	Context map[string]interface{} `yaml:"context,omitempty"`
+	User    map[string]interface{} `yaml:"user,omitempty"`
+}
+
+// GetKubeconfig fetches the kubeconfig of a cluster from its master node over ssh
+// the server address is set to the master reachable ip, or its yggdrasil ip if useYgg is set
+func GetKubeconfig(t deployer.TFPluginClient, clusterName, identity string, useYgg bool) (KubeConfig, error) {
+	target, err := GetSSHTarget(t, clusterName, "")
+	if err != nil {
+		return KubeConfig{}, err
+	}
+	if useYgg {
+		if target.YggIP == "" {
+			return KubeConfig{}, fmt.Errorf("master of cluster %s has no yggdrasil ip", clusterName)
+		}
+		target.Address = target.YggIP
+	}
+	if identity == "" {
+		identity, err = FindSSHIdentity(target.PublicKey)
+		if err != nil {
+			return KubeConfig{}, err
+		}
+	}
+	raw, err := SSHOutput(target, identity, "root", "cat", k3sConfigPath)
+	if err != nil {
+		return KubeConfig{}, errors.Wrapf(err, "could not read kubeconfig from master of cluster %s", clusterName)
+	}
+	return RewriteKubeconfig(raw, clusterName, target.Address)
+}
+
+// RewriteKubeconfig renames the cluster, context and user of a k3s kubeconfig after the cluster
+// and points its server to the given master address
+func RewriteKubeconfig(raw []byte, clusterName, address string) (KubeConfig, error) {
+	var config KubeConfig
+	err := yaml.Unmarshal(raw, &config)
+	if err != nil {
+		return KubeConfig{}, errors.Wrap(err, "invalid kubeconfig")
+	}
+	if len(config.Clusters) != 1 || len(config.Contexts) != 1 || len(config.Users) != 1 {
+		return KubeConfig{}, errors.New("kubeconfig must have exactly one cluster, context and user")
+	}
+
+	if config.Clusters[0].Cluster == nil || config.Contexts[0].Context == nil {
+		return KubeConfig{}, errors.New("kubeconfig cluster and context must not be empty")
+	}
+	config.Clusters[0].Name = clusterName
+	config.Clusters[0].Cluster["server"] = fmt.Sprintf("https://%s", net.JoinHostPort(address, k3sAPIPort))
+	config.Users[0].Name = clusterName
+	config.Contexts[0].Name = clusterName
+	config.Contexts[0].Context["cluster"] = clusterName
+	config.Contexts[0].Context["user"] = clusterName
+	config.CurrentContext = clusterName
+	return config, nil
+}
+
+// MergeKubeconfig adds the clusters, contexts and users of a kubeconfig to another one
+// entries with the same name are replaced, and the current context is switched to the added one
+func MergeKubeconfig(base, added KubeConfig) KubeConfig {
+	if base.APIVersion == "" {
+		base.APIVersion = added.APIVersion
+		base.Kind = added.Kind
+	}
+	base.Clusters = mergeKubeconfigEntries(base.Clusters, added.Clusters)
+	base.Contexts = mergeKubeconfigEntries(base.Contexts, added.Contexts)
+	base.Users = mergeKubeconfigEntries(base.Users, added.Users)
+	base.CurrentContext = added.CurrentContext
+	return base
+}
+
+// WriteKubeconfig writes a kubeconfig to a file, merging it with the file content if merge is set
+func WriteKubeconfig(config KubeConfig, path string, merge bool) error {
+	if merge {
+		content, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "could not read kubeconfig %s", path)
+		}
+		var base KubeConfig
+		if err := yaml.Unmarshal(content, &base); err != nil {
+			return errors.Wrapf(err, "invalid kubeconfig %s", path)
+		}
+		config = MergeKubeconfig(base, config)
+	}
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrapf(err, "could not create directory of kubeconfig %s", path)
+	}
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return errors.Wrapf(err, "could not write kubeconfig %s", path)
+	}
+	return nil
+}
+
+func mergeKubeconfigEntries(base, added []KubeConfigEntry) []KubeConfigEntry {
+	for _, entry := range added {
+		replaced := false
+		for i := range base {
+			if base[i].Name == entry.Name {
+				base[i] = entry
+				replaced = true
+			}
+		}
+		if !replaced {
+			base = append(base, entry)
+		}
+	}
+	return base
+}
@@ -0,0 +1,72 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+var k3sConfig = []byte(`apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Y2VydA==
+    server: https://127.0.0.1:6443
+  name: default
+contexts:
+- context:
+    cluster: default
+    user: default
+  name: default
+current-context: default
+kind: Config
+preferences: {}
+users:
+- name: default
+  user:
+    client-certificate-data: Y2VydA==
+    client-key-data: a2V5
+`)
+
+func TestRewriteKubeconfig(t *testing.T) {
+	config, err := RewriteKubeconfig(k3sConfig, "examplek8s", "300:e9c4::1")
+	assert.NoError(t, err)
+	assert.Equal(t, "examplek8s", config.Clusters[0].Name)
+	assert.Equal(t, "https://[300:e9c4::1]:6443", config.Clusters[0].Cluster["server"])
+	assert.Equal(t, "Y2VydA==", config.Clusters[0].Cluster["certificate-authority-data"])
+	assert.Equal(t, "examplek8s", config.Contexts[0].Context["cluster"])
+	assert.Equal(t, "examplek8s", config.Contexts[0].Context["user"])
+	assert.Equal(t, "examplek8s", config.Users[0].Name)
+	assert.Equal(t, "examplek8s", config.CurrentContext)
+
+	_, err = RewriteKubeconfig([]byte("apiVersion: v1\nkind: Config\n"), "examplek8s", "10.20.2.2")
+	assert.Error(t, err)
+}
+
+func TestWriteKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".kube", "config")
+	first, err := RewriteKubeconfig(k3sConfig, "first", "185.206.122.33")
+	assert.NoError(t, err)
+	assert.NoError(t, WriteKubeconfig(first, path, true))
+
+	second, err := RewriteKubeconfig(k3sConfig, "second", "185.206.122.34")
+	assert.NoError(t, err)
+	assert.NoError(t, WriteKubeconfig(second, path, true))
+
+	// redeploying a cluster with the same name replaces its entries
+	first.Clusters[0].Cluster["server"] = "https://185.206.122.35:6443"
+	assert.NoError(t, WriteKubeconfig(first, path, true))
+
+	var merged KubeConfig
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, yaml.Unmarshal(content, &merged))
+	assert.Len(t, merged.Clusters, 2)
+	assert.Len(t, merged.Contexts, 2)
+	assert.Len(t, merged.Users, 2)
+	assert.Equal(t, "https://185.206.122.35:6443", merged.Clusters[0].Cluster["server"])
+	assert.Equal(t, "first", merged.CurrentContext)
+}
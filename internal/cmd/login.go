@@ -11,10 +11,16 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/threefoldtech/tf-grid-cli/internal/config"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
 )
 
-// Login handles login command logic
-func Login() error {
+// Login handles login command logic, the configuration is saved to the named profile if it is set
+func Login(profile string) error {
+	path, err := config.GetProfileConfigPath(profile)
+	if err != nil {
+		return errors.Wrap(err, "failed to get configuration file")
+	}
+
 	scanner := bufio.NewReader(os.Stdin)
 
 	fmt.Print("Please enter your mnemonics: ")
@@ -24,7 +30,7 @@ func Login() error {
 	}
 	mnemonics = strings.TrimSpace(mnemonics)
 	if !bip39.IsMnemonicValid(mnemonics) {
-		return errors.New("failed to validate mnemonics")
+		return errkind.Errorf(errkind.Config, "failed to validate mnemonics")
 	}
 
 	fmt.Print("Please enter grid network (main,test): ")
@@ -35,13 +41,8 @@ func Login() error {
 	network = strings.TrimSpace(network)
 
 	if network != "dev" && network != "qa" && network != "test" && network != "main" {
-		return errors.New("invalid grid network, must be one of: dev, test, qa and main")
+		return errkind.Errorf(errkind.Validation, "invalid grid network, must be one of: dev, test, qa and main")
 	}
-	path, err := config.GetConfigPath()
-	if err != nil {
-		return errors.Wrap(err, "failed to get configuration file")
-	}
-
 	cfg := config.Config{}
 	cfg.Mnemonics = mnemonics
 	cfg.Network = network
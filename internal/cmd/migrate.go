@@ -0,0 +1,506 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/tf-grid-cli/internal/filters"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// MigrateProject migrates a vm or kubernetes project with MigrateVM or MigrateK8sCluster, it returns the project type
+func MigrateProject(ctx context.Context, t deployer.TFPluginClient, name string, node uint32, farm uint64, verifyTimeout time.Duration) (string, error) {
+	deploymentType, err := getProjectType(t, name)
+	if err != nil {
+		return "", err
+	}
+	if deploymentType == "kubernetes" {
+		_, err = MigrateK8sCluster(ctx, t, name, node, farm, verifyTimeout)
+	} else {
+		_, err = MigrateVM(ctx, t, name, node, farm, verifyTimeout)
+	}
+	return deploymentType, err
+}
+
+// MigrateVM deploys a copy of a vm project on another node, or a node of farm if node is 0,
+// then points the gateways proxying to the vm to the copy and cancels the old deployment.
+// the copy is deployed under a temporary project name, and renamed to the project once it is checked.
+// the copy is checked to be healthy, and to answer on ssh if verifyTimeout is set, before anything is switched.
+// disk contents are not copied
+func MigrateVM(ctx context.Context, t deployer.TFPluginClient, name string, node uint32, farm uint64, verifyTimeout time.Duration) (workloads.VM, error) {
+	dl, err := GetVM(t, name)
+	if err != nil {
+		return workloads.VM{}, err
+	}
+	oldNode, oldContract := dl.NodeID, dl.ContractID
+	if node == 0 {
+		disk := workloads.Disk{}
+		for _, d := range dl.Disks {
+			disk.SizeGB += d.SizeGB
+		}
+		vm, err := getVMWorkload(t, name)
+		if err != nil {
+			return workloads.VM{}, err
+		}
+		node, err = filters.GetAvailableNode(ctx, t.GridProxyClient, filters.BuildVMFilter(vm, disk, farm), NodeChecker(t))
+		if err != nil {
+			return workloads.VM{}, err
+		}
+	}
+	if node == oldNode {
+		return workloads.VM{}, errkind.Errorf(errkind.Validation, "vm %s is already deployed on node %d", name, node)
+	}
+	log.Warn().Msgf("disk contents of %s are not copied, the vm on node %d starts with empty disks", name, node)
+
+	network, err := prepareMigrationNetwork(ctx, t, dl.NetworkName, node)
+	if err != nil {
+		return workloads.VM{}, err
+	}
+
+	newDl := dl
+	newDl.SolutionType = migratingProjectName(name)
+	newDl.NodeID = node
+	newDl.NodeDeploymentID = nil
+	newDl.ContractID = 0
+	newDl.Vms = make([]workloads.VM, 0, len(dl.Vms))
+	for _, vm := range dl.Vms {
+		vm.IP, vm.ComputedIP, vm.ComputedIP6, vm.YggIP = "", "", "", ""
+		newDl.Vms = append(newDl.Vms, vm)
+	}
+
+	log.Info().Msgf("deploying copy of %s on node %d", name, node)
+	err = t.DeploymentDeployer.Deploy(ctx, &newDl)
+	if err != nil {
+		return workloads.VM{}, rollbackMigration(ctx, t, network, newDl.NodeDeploymentID, errors.Wrapf(err, "failed to deploy copy of %s on node %d", name, node))
+	}
+	newDeployments := map[uint32]uint64{node: newDl.ContractID}
+	if err := verifyMigration(ctx, t, newDeployments); err != nil {
+		return workloads.VM{}, rollbackMigration(ctx, t, network, newDeployments, err)
+	}
+	zosDl, err := getNodeDeployment(ctx, t, node, newDl.ContractID)
+	if err != nil {
+		return workloads.VM{}, rollbackMigration(ctx, t, network, newDeployments, err)
+	}
+	loaded, err := workloads.NewDeploymentFromZosDeployment(zosDl, node)
+	if err != nil {
+		return workloads.VM{}, rollbackMigration(ctx, t, network, newDeployments, err)
+	}
+	addresses := map[string]string{}
+	var resVM workloads.VM
+	for _, newVM := range loaded.Vms {
+		for _, oldVM := range dl.Vms {
+			if oldVM.Name == newVM.Name {
+				addMigratedAddresses(addresses, oldVM.ComputedIP, oldVM.ComputedIP6, oldVM.YggIP, oldVM.IP, newVM.ComputedIP, newVM.ComputedIP6, newVM.YggIP, newVM.IP)
+			}
+		}
+		if newVM.Name == name {
+			resVM = newVM
+		}
+		if verifyTimeout != 0 {
+			if err := WaitForVM(ctx, newVM, verifyTimeout); err != nil {
+				return workloads.VM{}, rollbackMigration(ctx, t, network, newDeployments, err)
+			}
+		}
+	}
+
+	if err := renameMigratedProject(ctx, t, newDeployments, name); err != nil {
+		return workloads.VM{}, rollbackMigration(ctx, t, network, newDeployments, err)
+	}
+	// gateways are switched to the copy, so it is kept and the switch finishes even if the command is interrupted
+	CommitContracts(t)
+	ctx = uninterruptible{ctx}
+	if err := switchMigrationGateways(ctx, t, addresses, network, newDeployments); err != nil {
+		return workloads.VM{}, err
+	}
+	log.Info().Msgf("canceling old deployment %d on node %d", oldContract, oldNode)
+	if err := t.SubstrateConn.CancelContract(t.Identity, oldContract); err != nil {
+		return workloads.VM{}, errkind.Wrap(errkind.PartialFailure, errors.Wrapf(err, "failed to cancel old deployment %d, cancel it manually", oldContract))
+	}
+	removeMigratedNetworkNode(ctx, t, dl.NetworkName, oldNode)
+	return resVM, nil
+}
+
+// MigrateK8sCluster deploys a copy of a kubernetes cluster with all its nodes on another node, or a node of farm if node is 0,
+// then points the gateways proxying to the cluster nodes to the copy and cancels the old deployments.
+// the copy is deployed under a temporary project name, and renamed to the project once it is checked.
+// the copy is checked to be healthy, and its master to answer on ssh if verifyTimeout is set, before anything is switched.
+// disk contents are not copied
+func MigrateK8sCluster(ctx context.Context, t deployer.TFPluginClient, name string, node uint32, farm uint64, verifyTimeout time.Duration) (workloads.K8sCluster, error) {
+	cluster, err := GetK8sCluster(t, name)
+	if err != nil {
+		return workloads.K8sCluster{}, err
+	}
+	if node == 0 {
+		node, err = filters.GetAvailableNode(ctx, t.GridProxyClient, filters.BuildK8sFilter(*cluster.Master, farm, uint(len(cluster.Workers)+1)), NodeChecker(t))
+		if err != nil {
+			return workloads.K8sCluster{}, err
+		}
+	}
+	if _, ok := cluster.NodeDeploymentID[node]; ok && len(cluster.NodeDeploymentID) == 1 {
+		return workloads.K8sCluster{}, errkind.Errorf(errkind.Validation, "cluster %s is already deployed on node %d", name, node)
+	}
+	log.Warn().Msgf("disk contents of %s are not copied, the cluster on node %d starts with empty disks", name, node)
+
+	// token and ssh key are not loaded with the cluster, they are read from the master environment
+	token, err := getZMachineEnv(t, cluster.Master.Node, cluster.Master.Name, name, "K3S_TOKEN")
+	if err != nil {
+		return workloads.K8sCluster{}, err
+	}
+	sshKey, err := getZMachineEnv(t, cluster.Master.Node, cluster.Master.Name, name, "SSH_KEY")
+	if err != nil {
+		return workloads.K8sCluster{}, err
+	}
+	network, err := prepareMigrationNetwork(ctx, t, cluster.NetworkName, node)
+	if err != nil {
+		return workloads.K8sCluster{}, err
+	}
+
+	master := migratedK8sNode(*cluster.Master, node)
+	newCluster := workloads.K8sCluster{
+		Master:       &master,
+		Token:        token,
+		SSHKey:       sshKey,
+		NetworkName:  cluster.NetworkName,
+		SolutionType: migratingProjectName(name),
+	}
+	for _, worker := range cluster.Workers {
+		newCluster.Workers = append(newCluster.Workers, migratedK8sNode(worker, node))
+	}
+
+	log.Info().Msgf("deploying copy of %s on node %d", name, node)
+	err = t.K8sDeployer.Deploy(ctx, &newCluster)
+	if err != nil {
+		return workloads.K8sCluster{}, rollbackMigration(ctx, t, network, newCluster.NodeDeploymentID, errors.Wrapf(err, "failed to deploy copy of %s on node %d", name, node))
+	}
+	newDeployments := newCluster.NodeDeploymentID
+	if err := verifyMigration(ctx, t, newDeployments); err != nil {
+		return workloads.K8sCluster{}, rollbackMigration(ctx, t, network, newDeployments, err)
+	}
+	if err := t.K8sDeployer.UpdateFromRemote(ctx, &newCluster); err != nil {
+		return workloads.K8sCluster{}, rollbackMigration(ctx, t, network, newDeployments, err)
+	}
+	if verifyTimeout != 0 {
+		if err := WaitForK8sNode(ctx, *newCluster.Master, verifyTimeout); err != nil {
+			return workloads.K8sCluster{}, rollbackMigration(ctx, t, network, newDeployments, err)
+		}
+	}
+	addresses := map[string]string{}
+	oldK8sNodes := append([]workloads.K8sNode{*cluster.Master}, cluster.Workers...)
+	newK8sNodes := append([]workloads.K8sNode{*newCluster.Master}, newCluster.Workers...)
+	for _, newK8sNode := range newK8sNodes {
+		for _, old := range oldK8sNodes {
+			if old.Name == newK8sNode.Name {
+				addMigratedAddresses(addresses, old.ComputedIP, old.ComputedIP6, old.YggIP, old.IP, newK8sNode.ComputedIP, newK8sNode.ComputedIP6, newK8sNode.YggIP, newK8sNode.IP)
+			}
+		}
+	}
+
+	if err := renameMigratedProject(ctx, t, newDeployments, name); err != nil {
+		return workloads.K8sCluster{}, rollbackMigration(ctx, t, network, newDeployments, err)
+	}
+	// gateways are switched to the copy, so it is kept and the switch finishes even if the command is interrupted
+	CommitContracts(t)
+	ctx = uninterruptible{ctx}
+	if err := switchMigrationGateways(ctx, t, addresses, network, newDeployments); err != nil {
+		return workloads.K8sCluster{}, err
+	}
+	for oldNode, oldContract := range cluster.NodeDeploymentID {
+		log.Info().Msgf("canceling old deployment %d on node %d", oldContract, oldNode)
+		if err := t.SubstrateConn.CancelContract(t.Identity, oldContract); err != nil {
+			return workloads.K8sCluster{}, errkind.Wrap(errkind.PartialFailure, errors.Wrapf(err, "failed to cancel old deployment %d, cancel it manually", oldContract))
+		}
+	}
+	for oldNode := range cluster.NodeDeploymentID {
+		if oldNode != node {
+			removeMigratedNetworkNode(ctx, t, cluster.NetworkName, oldNode)
+		}
+	}
+	// the migrated cluster is returned as loaded, the project listing may not show the renamed contracts yet
+	newCluster.SolutionType = name
+	newCluster.NodeDeploymentID = newDeployments
+	return newCluster, nil
+}
+
+// migrationNetwork is the network of a migrated project, with the node it was extended to for the copy, 0 if it was not
+type migrationNetwork struct {
+	name         string
+	extendedNode uint32
+}
+
+// prepareMigrationNetwork extends a network to the migration node if needed, and reserves the ips used there
+func prepareMigrationNetwork(ctx context.Context, t deployer.TFPluginClient, networkName string, node uint32) (migrationNetwork, error) {
+	migration := migrationNetwork{name: networkName}
+	network, err := LoadNetwork(ctx, t, networkName)
+	if err != nil {
+		return migration, err
+	}
+	if !workloads.Contains(network.Nodes, node) {
+		log.Info().Msgf("extending network %s to node %d", networkName, node)
+		network.Nodes = append(network.Nodes, node)
+		if err := updateNetwork(ctx, t, &network); err != nil {
+			return migration, err
+		}
+		migration.extendedNode = node
+	}
+	_, err = reserveNetworkIPs(ctx, t, network, []uint32{node})
+	if err != nil {
+		return migration, rollbackMigration(ctx, t, migration, nil, err)
+	}
+	return migration, nil
+}
+
+// removeMigratedNetworkNode removes a node left without workloads from a network, failures are only logged
+func removeMigratedNetworkNode(ctx context.Context, t deployer.TFPluginClient, networkName string, node uint32) {
+	if _, err := RemoveNetworkNode(ctx, t, networkName, node); err != nil {
+		log.Warn().Err(err).Msgf("network %s is kept on node %d", networkName, node)
+	}
+}
+
+// verifyMigration checks every workload of the migrated deployments is ok
+func verifyMigration(ctx context.Context, t deployer.TFPluginClient, deployments map[uint32]uint64) error {
+	for node, contractID := range deployments {
+		dl, err := getNodeDeployment(ctx, t, node, contractID)
+		if err != nil {
+			return err
+		}
+		for _, wl := range workloadsInfo(dl) {
+			if wl.State != string(gridtypes.StateOk) {
+				return fmt.Errorf("%s %s on node %d is %s: %s", wl.Type, wl.Name, node, wl.State, wl.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// rollbackMigration cancels the deployments created by a failed migration, and removes the node the network
+// was extended to for them. the old deployments are kept
+func rollbackMigration(ctx context.Context, t deployer.TFPluginClient, network migrationNetwork, deployments map[uint32]uint64, err error) error {
+	for node, contractID := range deployments {
+		if contractID == 0 {
+			continue
+		}
+		log.Info().Msgf("canceling deployment %d on node %d, the old deployment is kept", contractID, node)
+		if cerr := t.SubstrateConn.CancelContract(t.Identity, contractID); cerr != nil {
+			return errkind.Wrap(errkind.PartialFailure, errors.Wrapf(err, "failed to cancel deployment %d, cancel it manually: %s", contractID, cerr))
+		}
+	}
+	if network.extendedNode != 0 {
+		removeMigratedNetworkNode(uninterruptible{ctx}, t, network.name, network.extendedNode)
+	}
+	return err
+}
+
+// migratingProjectName is the project name of a copy until it is checked,
+// so commands looking up the project don't find both deployments
+func migratingProjectName(name string) string {
+	return name + "-migrating"
+}
+
+// renameMigratedProject sets the project name of the migrated deployments in their metadata and contracts
+func renameMigratedProject(ctx context.Context, t deployer.TFPluginClient, deployments map[uint32]uint64, name string) error {
+	d := deployer.NewDeployer(t, true)
+	for node, contractID := range deployments {
+		dl, err := getNodeDeployment(ctx, t, node, contractID)
+		if err != nil {
+			return err
+		}
+		dl.Metadata, err = setProjectName(dl.Metadata, name)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("renaming deployment %d on node %d to project %s", contractID, node, name)
+		_, err = d.Deploy(ctx, map[uint32]uint64{node: contractID}, map[uint32]gridtypes.Deployment{node: dl}, nil)
+		if err != nil {
+			return errors.Wrapf(err, "failed to rename deployment %d to project %s", contractID, name)
+		}
+		// the deployer updates the contract hash only, the contract data is listed by project name
+		dl, err = getNodeDeployment(ctx, t, node, contractID)
+		if err != nil {
+			return err
+		}
+		hash, err := dl.ChallengeHash()
+		if err != nil {
+			return errors.Wrapf(err, "could not hash deployment %d", contractID)
+		}
+		_, err = t.SubstrateConn.UpdateNodeContract(t.Identity, contractID, dl.Metadata, hex.EncodeToString(hash))
+		if err != nil {
+			return errkind.Wrap(errkind.Chain, errors.Wrapf(err, "failed to rename contract %d to project %s", contractID, name))
+		}
+	}
+	return nil
+}
+
+// setProjectName returns deployment metadata with its project name set
+func setProjectName(metadata, name string) (string, error) {
+	data, err := workloads.ParseDeploymentData(metadata)
+	if err != nil {
+		return "", errors.Wrap(err, "could not parse deployment metadata")
+	}
+	data.ProjectName = name
+	raw, err := json.Marshal(data)
+	return string(raw), err
+}
+
+// switchMigrationGateways switches the gateways to the migrated ips. if it fails, the switched gateways are
+// pointed back to the old ips and the migrated deployments are canceled
+func switchMigrationGateways(ctx context.Context, t deployer.TFPluginClient, addresses map[string]string, network migrationNetwork, deployments map[uint32]uint64) error {
+	err := switchGatewayBackends(ctx, t, addresses)
+	if err == nil {
+		return nil
+	}
+	log.Error().Err(err).Msg("failed to switch gateways, switching them back to the old deployment")
+	if rerr := switchGatewayBackends(ctx, t, reversedAddresses(addresses)); rerr != nil {
+		return errkind.Wrap(errkind.PartialFailure, errors.Wrapf(err, "switching gateways back failed, the old and migrated deployments are kept: %s", rerr))
+	}
+	return rollbackMigration(ctx, t, network, deployments, err)
+}
+
+// reversedAddresses maps the new ips of a migration back to the old ones
+func reversedAddresses(addresses map[string]string) map[string]string {
+	reversed := make(map[string]string, len(addresses))
+	for oldIP, newIP := range addresses {
+		reversed[newIP] = oldIP
+	}
+	return reversed
+}
+
+func migratedK8sNode(k8sNode workloads.K8sNode, node uint32) workloads.K8sNode {
+	k8sNode.Node = node
+	k8sNode.IP, k8sNode.ComputedIP, k8sNode.ComputedIP6, k8sNode.YggIP = "", "", "", ""
+	return k8sNode
+}
+
+// addMigratedAddresses maps the old public, yggdrasil and private ips of a vm to the new ones
+func addMigratedAddresses(addresses map[string]string, oldIPv4, oldIPv6, oldYgg, oldPrivate, newIPv4, newIPv6, newYgg, newPrivate string) {
+	pairs := [][2]string{{oldIPv4, newIPv4}, {oldIPv6, newIPv6}, {oldYgg, newYgg}, {oldPrivate, newPrivate}}
+	for _, pair := range pairs {
+		oldIP, newIP := configIP(pair[0]), configIP(pair[1])
+		if oldIP != "" && newIP != "" && oldIP != newIP {
+			addresses[oldIP] = newIP
+		}
+	}
+}
+
+// switchGatewayBackends points the backends of the gateways of the twin on old ips to the new ones
+// zos can't update gateways, so the changed gateways are removed then added back in the same contract
+func switchGatewayBackends(ctx context.Context, t deployer.TFPluginClient, addresses map[string]string) error {
+	if len(addresses) == 0 {
+		return nil
+	}
+	contracts, err := t.ContractsGetter.ListContractsByTwinID([]string{"Created, GracePeriod"})
+	if err != nil {
+		return err
+	}
+	for _, contract := range contracts.NodeContracts {
+		var deploymentData workloads.DeploymentData
+		err := json.Unmarshal([]byte(contract.DeploymentData), &deploymentData)
+		if err != nil {
+			return err
+		}
+		if deploymentData.Type != "Gateway Name" && deploymentData.Type != "Gateway Fqdn" {
+			continue
+		}
+		var contractID uint64
+		if _, err := fmt.Sscan(contract.ContractID, &contractID); err != nil {
+			return err
+		}
+		dl, err := getNodeDeployment(ctx, t, contract.NodeID, contractID)
+		if err != nil {
+			return err
+		}
+		switched := dl
+		switched.Workloads = append([]gridtypes.Workload{}, dl.Workloads...)
+		changed := map[string]bool{}
+		for i, wl := range dl.Workloads {
+			data, ok, err := switchWorkloadBackends(wl, addresses)
+			if err != nil {
+				return err
+			}
+			if ok {
+				switched.Workloads[i].Data = data
+				changed[string(wl.Name)] = true
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		log.Info().Msgf("switching gateway %s backends to the migrated ips", deploymentData.Name)
+		if err := recreateWorkloads(ctx, t, contract.NodeID, switched, dl, changed); err != nil {
+			return errors.Wrapf(err, "failed to switch gateway %s backends", deploymentData.Name)
+		}
+	}
+	return nil
+}
+
+// switchWorkloadBackends returns the data of a gateway workload with its backends switched to new ips, and whether it changed
+func switchWorkloadBackends(wl gridtypes.Workload, addresses map[string]string) (json.RawMessage, bool, error) {
+	dataI, err := wl.WorkloadData()
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "could not get workload %s data", wl.Name)
+	}
+	var backends *[]zos.Backend
+	switch data := dataI.(type) {
+	case *zos.GatewayNameProxy:
+		backends = &data.Backends
+	case *zos.GatewayFQDNProxy:
+		backends = &data.Backends
+	default:
+		return nil, false, nil
+	}
+	changed := false
+	for i, backend := range *backends {
+		switched := switchBackend(string(backend), addresses)
+		if switched != string(backend) {
+			(*backends)[i] = zos.Backend(switched)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, false, nil
+	}
+	raw, err := json.Marshal(dataI)
+	return raw, true, err
+}
+
+// switchBackend replaces the ip of a backend url, or ip:port with tls passthrough, if it is in addresses
+func switchBackend(backend string, addresses map[string]string) string {
+	if strings.Contains(backend, "://") {
+		u, err := url.Parse(backend)
+		if err != nil {
+			return backend
+		}
+		newIP, ok := addresses[u.Hostname()]
+		if !ok {
+			return backend
+		}
+		if port := u.Port(); port != "" {
+			u.Host = net.JoinHostPort(newIP, port)
+		} else if strings.Contains(newIP, ":") {
+			u.Host = "[" + newIP + "]"
+		} else {
+			u.Host = newIP
+		}
+		return u.String()
+	}
+	host, port, err := net.SplitHostPort(backend)
+	if err != nil {
+		return backend
+	}
+	if newIP, ok := addresses[host]; ok {
+		return net.JoinHostPort(newIP, port)
+	}
+	return backend
+}
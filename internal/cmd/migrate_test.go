@@ -0,0 +1,71 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestSwitchBackend(t *testing.T) {
+	addresses := map[string]string{
+		"185.206.122.10":   "185.206.122.20",
+		"2a02:1802:5e::10": "2a02:1802:5e::20",
+	}
+	assert.Equal(t, "http://185.206.122.20:9000", switchBackend("http://185.206.122.10:9000", addresses))
+	assert.Equal(t, "https://185.206.122.20/path", switchBackend("https://185.206.122.10/path", addresses))
+	assert.Equal(t, "http://[2a02:1802:5e::20]:80", switchBackend("http://[2a02:1802:5e::10]:80", addresses))
+	assert.Equal(t, "185.206.122.20:443", switchBackend("185.206.122.10:443", addresses))
+	assert.Equal(t, "http://185.206.122.11:9000", switchBackend("http://185.206.122.11:9000", addresses))
+	assert.Equal(t, "not a backend", switchBackend("not a backend", addresses))
+}
+
+func TestSwitchWorkloadBackends(t *testing.T) {
+	addresses := map[string]string{"185.206.122.10": "185.206.122.20"}
+	gateway := func(backends ...zos.Backend) gridtypes.Workload {
+		data, err := json.Marshal(zos.GatewayNameProxy{GatewayBase: zos.GatewayBase{Backends: backends}, Name: "site"})
+		assert.NoError(t, err)
+		return gridtypes.Workload{Name: "site", Type: zos.GatewayNameProxyType, Data: data}
+	}
+
+	data, changed, err := switchWorkloadBackends(gateway("http://185.206.122.10:9000", "http://185.206.122.11:9000"), addresses)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	var proxy zos.GatewayNameProxy
+	assert.NoError(t, json.Unmarshal(data, &proxy))
+	assert.Equal(t, []zos.Backend{"http://185.206.122.20:9000", "http://185.206.122.11:9000"}, proxy.Backends)
+
+	_, changed, err = switchWorkloadBackends(gateway("http://185.206.122.11:9000"), addresses)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestAddMigratedAddresses(t *testing.T) {
+	addresses := map[string]string{}
+	addMigratedAddresses(addresses, "185.206.122.10/24", "", "300:1::1", "10.20.2.2", "185.206.122.20/24", "", "300:2::1", "10.20.3.2")
+	assert.Equal(t, map[string]string{
+		"185.206.122.10": "185.206.122.20",
+		"300:1::1":       "300:2::1",
+		"10.20.2.2":      "10.20.3.2",
+	}, addresses)
+}
+
+func TestSetProjectName(t *testing.T) {
+	metadata, err := setProjectName(`{"type":"vm","name":"vm1","projectName":"vm1-migrating"}`, "vm1")
+	assert.NoError(t, err)
+	data, err := workloads.ParseDeploymentData(metadata)
+	assert.NoError(t, err)
+	assert.Equal(t, workloads.DeploymentData{Type: "vm", Name: "vm1", ProjectName: "vm1"}, data)
+
+	_, err = setProjectName("not json", "vm1")
+	assert.Error(t, err)
+}
+
+func TestReversedAddresses(t *testing.T) {
+	reversed := reversedAddresses(map[string]string{"185.206.122.10": "185.206.122.20", "10.20.2.2": "10.20.3.2"})
+	assert.Equal(t, map[string]string{"185.206.122.20": "185.206.122.10", "10.20.3.2": "10.20.2.2"}, reversed)
+}
@@ -0,0 +1,405 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// DefaultNetworkRange is the private ip range used for networks if not set
+const DefaultNetworkRange = "10.20.0.0/16"
+
+// projectNetworkName returns the name of the network created for a project
+func projectNetworkName(projectName string) string {
+	return fmt.Sprintf("%snetwork", projectName)
+}
+
+// ParseNetworkRange parses and validates a private network ip range
+func ParseNetworkRange(ipRange string) (gridtypes.IPNet, error) {
+	_, ipNet, err := net.ParseCIDR(ipRange)
+	if err != nil {
+		return gridtypes.IPNet{}, errkind.Wrap(errkind.Validation, errors.Wrapf(err, "invalid network range %s", ipRange))
+	}
+	if ipNet.IP.To4() == nil {
+		return gridtypes.IPNet{}, errkind.Errorf(errkind.Validation, "invalid network range %s, must be an ipv4 range", ipRange)
+	}
+	if ones, _ := ipNet.Mask.Size(); ones != 16 {
+		return gridtypes.IPNet{}, errkind.Errorf(errkind.Validation, "invalid network range %s, mask must be /16", ipRange)
+	}
+	if !ipNet.IP.IsPrivate() {
+		return gridtypes.IPNet{}, errkind.Errorf(errkind.Validation, "invalid network range %s, must be a private range", ipRange)
+	}
+	return gridtypes.NewIPNet(*ipNet), nil
+}
+
+// validateNetworkIP validates a private ip can be assigned in a network ip range
+func validateNetworkIP(ipRange gridtypes.IPNet, ip string) error {
+	parsedIP := net.ParseIP(ip).To4()
+	if parsedIP == nil {
+		return errkind.Errorf(errkind.Validation, "invalid ip %s, must be an ipv4", ip)
+	}
+	if !ipRange.Contains(parsedIP) {
+		return errkind.Errorf(errkind.Validation, "ip %s is not in network range %s", ip, ipRange.String())
+	}
+	if parsedIP[3] < 2 || parsedIP[3] > 254 {
+		return errkind.Errorf(errkind.Validation, "invalid ip %s, last octet must be between 2 and 254", ip)
+	}
+	return nil
+}
+
+// validateNodeIP validates a private ip is in the subnet assigned to a node in a network
+func validateNodeIP(network workloads.ZNet, node uint32, ip string) error {
+	subnet, ok := network.NodesIPRange[node]
+	if !ok {
+		return errkind.Errorf(errkind.NotFound, "node %d has no subnet in network %s", node, network.Name)
+	}
+	if !subnet.Contains(net.ParseIP(ip)) {
+		return errkind.Errorf(errkind.Validation, "ip %s is not in node %d subnet %s", ip, node, subnet.String())
+	}
+	return nil
+}
+
+// LoadNetwork loads a network with its nodes configuration from the grid
+func LoadNetwork(ctx context.Context, t deployer.TFPluginClient, networkName string) (workloads.ZNet, error) {
+	contracts, err := t.ContractsGetter.ListContractsByTwinID([]string{"Created, GracePeriod"})
+	if err != nil {
+		return workloads.ZNet{}, err
+	}
+	znet := workloads.ZNet{
+		Name:             networkName,
+		NodeDeploymentID: make(map[uint32]uint64),
+	}
+	for _, contract := range contracts.NodeContracts {
+		var deploymentData workloads.DeploymentData
+		err := json.Unmarshal([]byte(contract.DeploymentData), &deploymentData)
+		if err != nil {
+			return workloads.ZNet{}, err
+		}
+		if deploymentData.Type != "network" || deploymentData.Name != networkName {
+			continue
+		}
+		contractID, err := strconv.ParseUint(contract.ContractID, 0, 64)
+		if err != nil {
+			return workloads.ZNet{}, err
+		}
+		znet.SolutionType = deploymentData.ProjectName
+		znet.Nodes = append(znet.Nodes, contract.NodeID)
+		znet.NodeDeploymentID[contract.NodeID] = contractID
+	}
+	if len(znet.Nodes) == 0 {
+		return workloads.ZNet{}, errkind.Errorf(errkind.NotFound, "no network with name %s found", networkName)
+	}
+
+	err = t.NetworkDeployer.ReadNodesConfig(ctx, &znet)
+	if err != nil {
+		return workloads.ZNet{}, errors.Wrapf(err, "failed to read network %s nodes configuration", networkName)
+	}
+
+	// ip range, access node and external ip are not loaded with nodes configuration
+	for node, contractID := range znet.NodeDeploymentID {
+		nodeClient, err := t.NcPool.GetNodeClient(t.SubstrateConn, node)
+		if err != nil {
+			return workloads.ZNet{}, errors.Wrapf(err, "could not get node client: %d", node)
+		}
+		dl, err := nodeClient.DeploymentGet(ctx, contractID)
+		if err != nil {
+			return workloads.ZNet{}, errors.Wrapf(err, "could not get network deployment %d from node %d", contractID, node)
+		}
+		for _, wl := range dl.Workloads {
+			if wl.Type != zos.NetworkType || wl.Name != gridtypes.Name(networkName) {
+				continue
+			}
+			dataI, err := wl.WorkloadData()
+			if err != nil {
+				return workloads.ZNet{}, errors.Wrapf(err, "could not get network %s data", networkName)
+			}
+			data, ok := dataI.(*zos.Network)
+			if !ok {
+				return workloads.ZNet{}, fmt.Errorf("could not create network workload from data %v", dataI)
+			}
+			znet.IPRange = data.NetworkIPRange
+			znet.Description = wl.Description
+			for _, peer := range data.Peers {
+				if peer.Endpoint != "" {
+					continue
+				}
+				znet.PublicNodeID = node
+				if !isNodeSubnet(znet, peer.Subnet) {
+					externalIP := peer.Subnet
+					znet.ExternalIP = &externalIP
+				}
+			}
+		}
+	}
+	return znet, nil
+}
+
+// NetworkWorkload is a workload attached to a network
+type NetworkWorkload struct {
+	Name        string `json:"name"`
+	ProjectName string `json:"project_name"`
+	NodeID      uint32 `json:"node_id"`
+	ContractID  uint64 `json:"contract_id"`
+	IP          string `json:"ip"`
+}
+
+// NetworkInfo describes a deployed network
+type NetworkInfo struct {
+	Name        string            `json:"name"`
+	ProjectName string            `json:"project_name"`
+	IPRange     string            `json:"ip_range"`
+	Subnets     map[uint32]string `json:"subnets"`
+	AccessNode  uint32            `json:"access_node,omitempty"`
+	ExternalIP  string            `json:"external_ip,omitempty"`
+	Workloads   []NetworkWorkload `json:"workloads"`
+}
+
+// GetNetwork returns a deployed network with its nodes, access point and member workloads
+func GetNetwork(ctx context.Context, t deployer.TFPluginClient, name string) (NetworkInfo, error) {
+	znet, err := LoadNetwork(ctx, t, name)
+	if err != nil {
+		return NetworkInfo{}, err
+	}
+	members, err := listNetworkWorkloads(ctx, t, znet)
+	if err != nil {
+		return NetworkInfo{}, err
+	}
+	info := NetworkInfo{
+		Name:        znet.Name,
+		ProjectName: znet.SolutionType,
+		IPRange:     znet.IPRange.String(),
+		Subnets:     make(map[uint32]string),
+		AccessNode:  znet.PublicNodeID,
+		Workloads:   members,
+	}
+	for node, subnet := range znet.NodesIPRange {
+		info.Subnets[node] = subnet.String()
+	}
+	if znet.ExternalIP != nil {
+		info.ExternalIP = znet.ExternalIP.String()
+	}
+	return info, nil
+}
+
+// AddNetworkNode extends a deployed network to a new node
+// if the network has wireguard access, the returned network holds a new wg-quick config
+func AddNetworkNode(ctx context.Context, t deployer.TFPluginClient, name string, node uint32) (workloads.ZNet, error) {
+	znet, err := LoadNetwork(ctx, t, name)
+	if err != nil {
+		return workloads.ZNet{}, err
+	}
+	if workloads.Contains(znet.Nodes, node) {
+		return workloads.ZNet{}, errkind.Errorf(errkind.Validation, "node %d is already in network %s", node, name)
+	}
+	znet.Nodes = append(znet.Nodes, node)
+	err = updateNetwork(ctx, t, &znet)
+	if err != nil {
+		return workloads.ZNet{}, err
+	}
+	return znet, nil
+}
+
+// RemoveNetworkNode removes a node without workloads attached from a deployed network
+// if the network has wireguard access, the returned network holds a new wg-quick config
+func RemoveNetworkNode(ctx context.Context, t deployer.TFPluginClient, name string, node uint32) (workloads.ZNet, error) {
+	znet, err := LoadNetwork(ctx, t, name)
+	if err != nil {
+		return workloads.ZNet{}, err
+	}
+	contractID, ok := znet.NodeDeploymentID[node]
+	if !ok {
+		return workloads.ZNet{}, errkind.Errorf(errkind.Validation, "node %d is not in network %s", node, name)
+	}
+	if len(znet.Nodes) == 1 {
+		return workloads.ZNet{}, errkind.Errorf(errkind.Validation, "node %d is the last node of network %s, cancel its project instead", node, name)
+	}
+	members, err := listNetworkWorkloads(ctx, t, znet)
+	if err != nil {
+		return workloads.ZNet{}, err
+	}
+	for _, member := range members {
+		if member.NodeID == node {
+			return workloads.ZNet{}, errkind.Errorf(errkind.Validation, "workload %s of project %s is using network %s on node %d", member.Name, member.ProjectName, name, node)
+		}
+	}
+
+	if znet.PublicNodeID == node {
+		znet.PublicNodeID = 0
+	}
+	nodes := []uint32{}
+	for _, n := range znet.Nodes {
+		if n != node {
+			nodes = append(nodes, n)
+		}
+	}
+	znet.Nodes = nodes
+	delete(znet.NodeDeploymentID, node)
+	delete(znet.NodesIPRange, node)
+	delete(znet.Keys, node)
+	delete(znet.WGPort, node)
+
+	err = updateNetwork(ctx, t, &znet)
+	if err != nil {
+		return workloads.ZNet{}, err
+	}
+	// the deployer only updates the remaining nodes, the removed node deployment is cancelled here
+	log.Info().Msgf("cancelling network deployment on node %d", node)
+	err = t.SubstrateConn.CancelContract(t.Identity, contractID)
+	if err != nil {
+		return workloads.ZNet{}, errkind.Wrap(errkind.PartialFailure, errors.Wrapf(err, "failed to cancel network contract %d on node %d", contractID, node))
+	}
+	return znet, nil
+}
+
+// EnableWGAccess enables wireguard access to a network through a node with a public config
+func EnableWGAccess(ctx context.Context, t deployer.TFPluginClient, znet *workloads.ZNet) error {
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		return errors.Wrap(err, "failed to generate wireguard private key")
+	}
+	znet.AddWGAccess = true
+	znet.ExternalSK = key
+
+	if znet.PublicNodeID == 0 {
+		node, err := getAccessNode(ctx, t, znet.Nodes)
+		if err != nil {
+			return errors.Wrap(err, "failed to find a node with public config to access the network")
+		}
+		znet.PublicNodeID = node
+	}
+	if !workloads.Contains(znet.Nodes, znet.PublicNodeID) {
+		znet.Nodes = append(znet.Nodes, znet.PublicNodeID)
+	}
+	log.Info().Msgf("network %s access node: %d", znet.Name, znet.PublicNodeID)
+	return nil
+}
+
+// NetworkWGConfig adds a new wireguard access to a deployed network and returns its wg-quick config
+// any previously generated config for the network stops working
+func NetworkWGConfig(ctx context.Context, t deployer.TFPluginClient, projectName string) (string, error) {
+	znet, err := LoadNetwork(ctx, t, projectNetworkName(projectName))
+	if err != nil {
+		return "", err
+	}
+	err = EnableWGAccess(ctx, t, &znet)
+	if err != nil {
+		return "", err
+	}
+	err = updateNetwork(ctx, t, &znet)
+	if err != nil {
+		return "", err
+	}
+	return znet.AccessWGConfig, nil
+}
+
+// updateNetwork redeploys a loaded network
+// wireguard access private key is not stored on the grid, so a new access config is generated
+func updateNetwork(ctx context.Context, t deployer.TFPluginClient, znet *workloads.ZNet) error {
+	if znet.AddWGAccess && znet.ExternalSK == (wgtypes.Key{}) {
+		log.Warn().Msgf("network %s wireguard access is regenerated, previous configs stop working", znet.Name)
+		if err := EnableWGAccess(ctx, t, znet); err != nil {
+			return err
+		}
+	}
+	log.Info().Msg("updating network")
+	err := t.NetworkDeployer.Deploy(ctx, znet)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update network %s", znet.Name)
+	}
+	return nil
+}
+
+// listNetworkWorkloads lists the vms and kubernetes nodes attached to a network
+func listNetworkWorkloads(ctx context.Context, t deployer.TFPluginClient, znet workloads.ZNet) ([]NetworkWorkload, error) {
+	contracts, err := t.ContractsGetter.ListContractsByTwinID([]string{"Created, GracePeriod"})
+	if err != nil {
+		return nil, err
+	}
+	members := []NetworkWorkload{}
+	for _, contract := range contracts.NodeContracts {
+		if !workloads.Contains(znet.Nodes, contract.NodeID) {
+			continue
+		}
+		var deploymentData workloads.DeploymentData
+		err := json.Unmarshal([]byte(contract.DeploymentData), &deploymentData)
+		if err != nil {
+			return nil, err
+		}
+		if deploymentData.Type != "vm" && deploymentData.Type != "kubernetes" {
+			continue
+		}
+		contractID, err := strconv.ParseUint(contract.ContractID, 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		nodeClient, err := t.NcPool.GetNodeClient(t.SubstrateConn, contract.NodeID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get node client: %d", contract.NodeID)
+		}
+		dl, err := nodeClient.DeploymentGet(ctx, contractID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get deployment %d from node %d", contractID, contract.NodeID)
+		}
+		for _, wl := range dl.Workloads {
+			if wl.Type != zos.ZMachineType {
+				continue
+			}
+			dataI, err := wl.WorkloadData()
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not get workload %s data", wl.Name)
+			}
+			data, ok := dataI.(*zos.ZMachine)
+			if !ok {
+				return nil, fmt.Errorf("could not create vm workload from data %v", dataI)
+			}
+			for _, inf := range data.Network.Interfaces {
+				if inf.Network != gridtypes.Name(znet.Name) {
+					continue
+				}
+				members = append(members, NetworkWorkload{
+					Name:        wl.Name.String(),
+					ProjectName: deploymentData.ProjectName,
+					NodeID:      contract.NodeID,
+					ContractID:  contractID,
+					IP:          inf.IP.String(),
+				})
+			}
+		}
+	}
+	return members, nil
+}
+
+// getAccessNode returns the first node with a public ipv4 config, or any public node on the grid
+func getAccessNode(ctx context.Context, t deployer.TFPluginClient, nodes []uint32) (uint32, error) {
+	for _, node := range nodes {
+		nodeInfo, err := t.GridProxyClient.Node(node)
+		if err != nil {
+			return 0, errors.Wrapf(err, "could not get node %d data from the grid proxy", node)
+		}
+		if nodeInfo.PublicConfig.Ipv4 != "" {
+			return node, nil
+		}
+	}
+	return deployer.GetPublicNode(ctx, t.GridProxyClient, nil)
+}
+
+func isNodeSubnet(znet workloads.ZNet, subnet gridtypes.IPNet) bool {
+	for _, nodeSubnet := range znet.NodesIPRange {
+		if nodeSubnet.String() == subnet.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+func TestParseNetworkRange(t *testing.T) {
+	t.Run("valid range", func(t *testing.T) {
+		ipRange, err := ParseNetworkRange("10.30.0.0/16")
+		assert.NoError(t, err)
+		assert.Equal(t, ipRange.String(), "10.30.0.0/16")
+	})
+	t.Run("invalid mask", func(t *testing.T) {
+		_, err := ParseNetworkRange("10.30.0.0/24")
+		assert.Error(t, err)
+		assert.Equal(t, errkind.Validation, errkind.Of(err))
+	})
+	t.Run("invalid cidr", func(t *testing.T) {
+		_, err := ParseNetworkRange("10.30.0.0")
+		assert.Error(t, err)
+		assert.Equal(t, errkind.Validation, errkind.Of(err))
+	})
+	t.Run("public range", func(t *testing.T) {
+		_, err := ParseNetworkRange("93.184.0.0/16")
+		assert.Error(t, err)
+	})
+	t.Run("ipv6 range", func(t *testing.T) {
+		_, err := ParseNetworkRange("fd00::/16")
+		assert.Error(t, err)
+	})
+}
+
+func TestValidateNetworkIP(t *testing.T) {
+	ipRange, err := ParseNetworkRange("10.30.0.0/16")
+	assert.NoError(t, err)
+
+	assert.NoError(t, validateNetworkIP(ipRange, "10.30.2.5"))
+	assert.Error(t, validateNetworkIP(ipRange, "10.20.2.5"))
+	assert.Error(t, validateNetworkIP(ipRange, "10.30.2.1"))
+	assert.Error(t, validateNetworkIP(ipRange, "invalid"))
+}
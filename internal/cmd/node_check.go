@@ -0,0 +1,29 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/tf-grid-cli/internal/filters"
+)
+
+// CheckNodes enables checking candidate nodes answer over rmb before picking them for a deployment
+var CheckNodes = true
+
+// NodeChecker returns a checker calling zos.system.version on candidate nodes over rmb,
+// so nodes reported up by the grid proxy but not answering are skipped before any contract is created.
+// it returns nil if CheckNodes is disabled
+func NodeChecker(t deployer.TFPluginClient) filters.NodeChecker {
+	if !CheckNodes {
+		return nil
+	}
+	return func(ctx context.Context, nodeID uint32) error {
+		nodeClient, err := t.NcPool.GetNodeClient(t.SubstrateConn, nodeID)
+		if err != nil {
+			return errors.Wrapf(err, "could not get node %d client", nodeID)
+		}
+		return nodeClient.IsNodeUp(ctx)
+	}
+}
@@ -0,0 +1,325 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// Project is every deployment and name contract deployed under a project name
+type Project struct {
+	Name          string              `json:"name"`
+	Deployments   []ProjectDeployment `json:"deployments"`
+	NameContracts []NameContract      `json:"name_contracts"`
+}
+
+// ProjectDeployment is a node contract of a project with its workloads as reported by the node
+type ProjectDeployment struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	NodeID     uint32         `json:"node_id"`
+	ContractID uint64         `json:"contract_id"`
+	Workloads  []WorkloadInfo `json:"workloads"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// WorkloadInfo is a workload of a deployment with its state on the node
+type WorkloadInfo struct {
+	Name   string          `json:"name"`
+	Type   string          `json:"type"`
+	State  string          `json:"state"`
+	Error  string          `json:"error,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// WorkloadState is the state of a workload of a project deployment on its node
+type WorkloadState struct {
+	Deployment string `json:"deployment"`
+	NodeID     uint32 `json:"node_id"`
+	ContractID uint64 `json:"contract_id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	State      string `json:"state"`
+	Error      string `json:"error,omitempty"`
+}
+
+// stateUnknown is the state of the workloads of a deployment that could not be fetched from its node
+const stateUnknown = "unknown"
+
+// Failed returns true if the workload is in error or its state could not be fetched
+func (s WorkloadState) Failed() bool {
+	return s.State == string(gridtypes.StateError) || s.State == stateUnknown
+}
+
+// NameContract is a name contract reserving a gateway name of a project
+type NameContract struct {
+	Name       string `json:"name"`
+	ContractID uint64 `json:"contract_id"`
+}
+
+// GetProject loads every node and name contract of a project, and the workloads of each deployment from its node
+// deployments that could not be fetched from their node are reported with their error
+func GetProject(ctx context.Context, t deployer.TFPluginClient, projectName string) (Project, error) {
+	return loadProject(ctx, t, projectName, "")
+}
+
+// GetWorkloadStates gets the state of every workload of the project deployment with the given type and name from its node
+func GetWorkloadStates(ctx context.Context, t deployer.TFPluginClient, name, deploymentType string) ([]WorkloadState, error) {
+	project, err := loadProject(ctx, t, name, deploymentType)
+	if err != nil {
+		return nil, err
+	}
+	if len(project.Deployments) == 0 {
+		return nil, errkind.Errorf(errkind.NotFound, "no %s with name %s found", deploymentType, name)
+	}
+	return project.WorkloadStates(), nil
+}
+
+// WorkloadStates returns the state of every workload of the project deployments
+// deployments that could not be fetched from their node are reported as a single workload with unknown state
+func (p Project) WorkloadStates() []WorkloadState {
+	states := []WorkloadState{}
+	for _, dl := range p.Deployments {
+		if dl.Error != "" {
+			states = append(states, WorkloadState{
+				Deployment: dl.Name,
+				NodeID:     dl.NodeID,
+				ContractID: dl.ContractID,
+				Name:       dl.Name,
+				Type:       dl.Type,
+				State:      stateUnknown,
+				Error:      dl.Error,
+			})
+			continue
+		}
+		for _, wl := range dl.Workloads {
+			states = append(states, WorkloadState{
+				Deployment: dl.Name,
+				NodeID:     dl.NodeID,
+				ContractID: dl.ContractID,
+				Name:       wl.Name,
+				Type:       wl.Type,
+				State:      wl.State,
+				Error:      wl.Error,
+			})
+		}
+	}
+	return states
+}
+
+// FailedWorkloads returns the workloads in error or with unknown state
+func FailedWorkloads(states []WorkloadState) []WorkloadState {
+	failed := []WorkloadState{}
+	for _, state := range states {
+		if state.Failed() {
+			failed = append(failed, state)
+		}
+	}
+	return failed
+}
+
+// loadProject loads a project, only fetching the deployments named after the project with the given type if it is set
+func loadProject(ctx context.Context, t deployer.TFPluginClient, projectName, deploymentType string) (Project, error) {
+	nodeContracts, nameContracts, err := listProjectContracts(t, projectName)
+	if err != nil {
+		return Project{}, err
+	}
+
+	project := Project{
+		Name:          projectName,
+		Deployments:   []ProjectDeployment{},
+		NameContracts: []NameContract{},
+	}
+	for _, contract := range nodeContracts {
+		if deploymentType != "" && (contract.deploymentType != deploymentType || contract.name != projectName) {
+			continue
+		}
+		deployment := ProjectDeployment{
+			Name:       contract.name,
+			Type:       contract.deploymentType,
+			NodeID:     contract.nodeID,
+			ContractID: contract.contractID,
+			Workloads:  []WorkloadInfo{},
+		}
+		dl, err := getNodeDeployment(ctx, t, contract.nodeID, contract.contractID)
+		if err != nil {
+			deployment.Error = err.Error()
+		} else {
+			deployment.Workloads = workloadsInfo(dl)
+		}
+		project.Deployments = append(project.Deployments, deployment)
+	}
+	if deploymentType == "" {
+		project.NameContracts = nameContracts
+	}
+	return project, nil
+}
+
+// projectContract is a node contract of a project with its deployment data
+type projectContract struct {
+	name           string
+	deploymentType string
+	nodeID         uint32
+	contractID     uint64
+}
+
+// listProjectContracts lists the node and name contracts of a project
+func listProjectContracts(t deployer.TFPluginClient, projectName string) ([]projectContract, []NameContract, error) {
+	contracts, err := t.ContractsGetter.ListContractsOfProjectName(projectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(contracts.NodeContracts) == 0 && len(contracts.NameContracts) == 0 {
+		return nil, nil, errkind.Errorf(errkind.NotFound, "no project with name %s found", projectName)
+	}
+
+	nodeContracts := make([]projectContract, 0, len(contracts.NodeContracts))
+	for _, contract := range contracts.NodeContracts {
+		var deploymentData workloads.DeploymentData
+		err := json.Unmarshal([]byte(contract.DeploymentData), &deploymentData)
+		if err != nil {
+			return nil, nil, err
+		}
+		contractID, err := strconv.ParseUint(contract.ContractID, 0, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		nodeContracts = append(nodeContracts, projectContract{
+			name:           deploymentData.Name,
+			deploymentType: deploymentData.Type,
+			nodeID:         contract.NodeID,
+			contractID:     contractID,
+		})
+	}
+	nameContracts := make([]NameContract, 0, len(contracts.NameContracts))
+	for _, contract := range contracts.NameContracts {
+		contractID, err := strconv.ParseUint(contract.ContractID, 0, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		nameContracts = append(nameContracts, NameContract{Name: contract.Name, ContractID: contractID})
+	}
+	return nodeContracts, nameContracts, nil
+}
+
+func getNodeDeployment(ctx context.Context, t deployer.TFPluginClient, nodeID uint32, contractID uint64) (gridtypes.Deployment, error) {
+	nodeClient, err := t.NcPool.GetNodeClient(t.SubstrateConn, nodeID)
+	if err != nil {
+		return gridtypes.Deployment{}, errors.Wrapf(err, "could not get node %d client", nodeID)
+	}
+	dl, err := nodeClient.DeploymentGet(ctx, contractID)
+	if err != nil {
+		return gridtypes.Deployment{}, errors.Wrapf(err, "could not get deployment %d from node %d", contractID, nodeID)
+	}
+	return dl, nil
+}
+
+func workloadsInfo(dl gridtypes.Deployment) []WorkloadInfo {
+	infos := make([]WorkloadInfo, 0, len(dl.Workloads))
+	for _, wl := range dl.Workloads {
+		info := WorkloadInfo{
+			Name:  string(wl.Name),
+			Type:  string(wl.Type),
+			State: string(wl.Result.State),
+			Error: wl.Result.Error,
+			Data:  redactWorkloadData(wl),
+		}
+		if len(wl.Result.Data) != 0 && string(wl.Result.Data) != "null" {
+			info.Result = wl.Result.Data
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// redactWorkloadData returns the workload data without its secrets: the network wireguard private key,
+// zdb and qsfs backend passwords, qsfs encryption keys and the k3s token of kubernetes nodes
+func redactWorkloadData(wl gridtypes.Workload) json.RawMessage {
+	switch wl.Type {
+	case zos.NetworkType, zos.ZDBType, zos.QuantumSafeFSType, zos.ZMachineType:
+	default:
+		return wl.Data
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(wl.Data, &data); err != nil {
+		return nil
+	}
+	redactSecrets(data)
+	redacted, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+// RedactDeployment returns a copy of a deployment to print, with its zdb passwords,
+// qsfs encryption keys and qsfs backend passwords cleared
+func RedactDeployment(dl workloads.Deployment) workloads.Deployment {
+	zdbs := dl.Zdbs
+	dl.Zdbs = nil
+	for _, zdb := range zdbs {
+		dl.Zdbs = append(dl.Zdbs, RedactZDB(zdb))
+	}
+	qsfss := dl.QSFS
+	dl.QSFS = nil
+	for _, qsfs := range qsfss {
+		qsfs.EncryptionKey = ""
+		qsfs.Metadata.EncryptionKey = ""
+		qsfs.Metadata.Backends = redactBackends(qsfs.Metadata.Backends)
+		groups := qsfs.Groups
+		qsfs.Groups = nil
+		for _, group := range groups {
+			qsfs.Groups = append(qsfs.Groups, workloads.Group{Backends: redactBackends(group.Backends)})
+		}
+		dl.QSFS = append(dl.QSFS, qsfs)
+	}
+	return dl
+}
+
+// RedactZDB returns a zdb to print with its password cleared
+func RedactZDB(zdb workloads.ZDB) workloads.ZDB {
+	zdb.Password = ""
+	return zdb
+}
+
+func redactBackends(backends workloads.Backends) workloads.Backends {
+	var redacted workloads.Backends
+	for _, backend := range backends {
+		backend.Password = ""
+		redacted = append(redacted, backend)
+	}
+	return redacted
+}
+
+// redactSecrets deletes the secret fields of workload data at any depth
+func redactSecrets(data map[string]interface{}) {
+	delete(data, "wireguard_private_key")
+	delete(data, "password")
+	if encryption, ok := data["encryption"].(map[string]interface{}); ok {
+		delete(encryption, "key")
+	}
+	if env, ok := data["env"].(map[string]interface{}); ok {
+		delete(env, "K3S_TOKEN")
+	}
+	for _, value := range data {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			redactSecrets(value)
+		case []interface{}:
+			for _, item := range value {
+				if item, ok := item.(map[string]interface{}); ok {
+					redactSecrets(item)
+				}
+			}
+		}
+	}
+}
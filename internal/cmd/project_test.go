@@ -0,0 +1,142 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestWorkloadsInfo(t *testing.T) {
+	dl := gridtypes.Deployment{
+		Workloads: []gridtypes.Workload{
+			{
+				Name: "network",
+				Type: zos.NetworkType,
+				Data: json.RawMessage(`{"subnet":"10.20.2.0/24","wireguard_private_key":"secret"}`),
+				Result: gridtypes.Result{
+					State: gridtypes.StateOk,
+				},
+			},
+			{
+				Name: "vm",
+				Type: zos.ZMachineType,
+				Data: json.RawMessage(`{"flist":"https://hub.grid.tf/tf-official-apps/base:latest.flist"}`),
+				Result: gridtypes.Result{
+					State: gridtypes.StateError,
+					Error: "failed to download flist",
+				},
+			},
+		},
+	}
+
+	infos := workloadsInfo(dl)
+	assert.Len(t, infos, 2)
+	assert.Equal(t, "network", infos[0].Name)
+	assert.Equal(t, "ok", infos[0].State)
+	assert.NotContains(t, string(infos[0].Data), "secret")
+	assert.Contains(t, string(infos[0].Data), "10.20.2.0/24")
+	assert.Nil(t, infos[0].Result)
+
+	assert.Equal(t, "zmachine", infos[1].Type)
+	assert.Equal(t, "error", infos[1].State)
+	assert.Equal(t, "failed to download flist", infos[1].Error)
+	assert.JSONEq(t, string(dl.Workloads[1].Data), string(infos[1].Data))
+
+	// secrets of zdbs, qsfs and kubernetes nodes are redacted too
+	dl = gridtypes.Deployment{
+		Workloads: []gridtypes.Workload{
+			{
+				Name: "zdb",
+				Type: zos.ZDBType,
+				Data: json.RawMessage(`{"size":1073741824,"mode":"user","password":"secret","public":false}`),
+			},
+			{
+				Name: "qsfs",
+				Type: zos.QuantumSafeFSType,
+				Data: json.RawMessage(`{"cache":1073741824,"config":{"encryption":{"algorithm":"AES","key":"secret"},
+					"meta":{"type":"zdb","config":{"prefix":"qsfs","encryption":{"algorithm":"AES","key":"secret"},
+					"backends":[{"address":"[2a02:1802:5e::1]:9900","namespace":"ns1","password":"secret"}]}},
+					"groups":[{"backends":[{"address":"[2a02:1802:5e::2]:9900","namespace":"ns2","password":"secret"}]}]}}`),
+			},
+			{
+				Name: "master",
+				Type: zos.ZMachineType,
+				Data: json.RawMessage(`{"flist":"https://hub.grid.tf/tf-official-apps/base:latest.flist","env":{"K3S_TOKEN":"secret","SSH_KEY":"ssh-ed25519 AAAA"}}`),
+			},
+		},
+	}
+
+	infos = workloadsInfo(dl)
+	assert.Len(t, infos, 3)
+	for _, info := range infos {
+		assert.NotContains(t, string(info.Data), "secret", info.Name)
+	}
+	assert.Contains(t, string(infos[0].Data), `"mode":"user"`)
+	assert.Contains(t, string(infos[1].Data), `"namespace":"ns1"`)
+	assert.Contains(t, string(infos[1].Data), `"algorithm":"AES"`)
+	assert.Contains(t, string(infos[2].Data), "ssh-ed25519 AAAA")
+}
+
+func TestWorkloadStates(t *testing.T) {
+	project := Project{
+		Name: "vm",
+		Deployments: []ProjectDeployment{
+			{
+				Name:   "vm",
+				Type:   "vm",
+				NodeID: 11,
+				Workloads: []WorkloadInfo{
+					{Name: "vm", Type: "zmachine", State: "error", Error: "failed to download flist"},
+					{Name: "disk", Type: "zmount", State: "ok"},
+				},
+			},
+			{
+				Name:   "vmnetwork",
+				Type:   "network",
+				NodeID: 12,
+				Error:  "could not get deployment 20 from node 12",
+			},
+		},
+	}
+
+	states := project.WorkloadStates()
+	assert.Len(t, states, 3)
+	assert.Equal(t, WorkloadState{Deployment: "vm", NodeID: 11, Name: "disk", Type: "zmount", State: "ok"}, states[1])
+	assert.Equal(t, "unknown", states[2].State)
+
+	failed := FailedWorkloads(states)
+	assert.Len(t, failed, 2)
+	assert.Equal(t, "vm", failed[0].Name)
+	assert.Equal(t, "vmnetwork", failed[1].Name)
+}
+
+func TestRedactDeployment(t *testing.T) {
+	backend := workloads.Backend{Address: "[2a02:1802:5e::1]:9900", Namespace: "ns1", Password: "secret"}
+	dl := workloads.Deployment{
+		Name: "vm",
+		Zdbs: []workloads.ZDB{{Name: "zdb", Password: "secret"}},
+		QSFS: []workloads.QSFS{{
+			Name:          "qsfs",
+			EncryptionKey: "secret",
+			Metadata:      workloads.Metadata{EncryptionKey: "secret", Backends: workloads.Backends{backend}},
+			Groups:        workloads.Groups{{Backends: workloads.Backends{backend, backend}}},
+		}},
+	}
+
+	redacted := RedactDeployment(dl)
+	raw, err := json.Marshal(redacted)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(raw), "secret")
+	assert.Contains(t, string(raw), "ns1")
+	assert.Len(t, redacted.QSFS[0].Groups[0].Backends, 2)
+	assert.Equal(t, "secret", dl.QSFS[0].Groups[0].Backends[0].Password)
+	assert.Equal(t, "secret", dl.Zdbs[0].Password)
+	assert.Nil(t, RedactDeployment(workloads.Deployment{}).QSFS)
+
+	assert.Equal(t, "", RedactZDB(workloads.ZDB{Name: "zdb", Password: "secret"}).Password)
+}
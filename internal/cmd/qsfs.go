@@ -0,0 +1,179 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+const (
+	// QSFSBackendNodes is the number of nodes qsfs zdb backends are spread on
+	QSFSBackendNodes = 4
+	// QSFSCacheSize is the size of qsfs cache on the vm node in mb
+	QSFSCacheSize = 1024
+
+	qsfsMinimalShards    = 2
+	qsfsMetaZDBSize      = 1
+	qsfsMaxZDBDataDir    = 512
+	qsfsEncryption       = "AES"
+	qsfsCompression      = "snappy"
+	qsfsMetadataType     = "zdb"
+	qsfsEncryptionKeyLen = 32
+)
+
+// BuildQSFSZDBs builds the zdbs deployed on each qsfs backend node
+// a data zdb holding the node shard and a metadata zdb
+func BuildQSFSZDBs(name string, size int) ([]workloads.ZDB, error) {
+	dataSize := (size + qsfsMinimalShards - 1) / qsfsMinimalShards
+	password, err := randomHex(16)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate zdb password")
+	}
+	return []workloads.ZDB{
+		{
+			Name:     fmt.Sprintf("%sdata", name),
+			Size:     dataSize,
+			Mode:     zos.ZDBModeSeq,
+			Password: password,
+			Public:   true,
+		},
+		{
+			Name:     fmt.Sprintf("%smeta", name),
+			Size:     qsfsMetaZDBSize,
+			Mode:     zos.ZDBModeUser,
+			Password: password,
+			Public:   true,
+		},
+	}, nil
+}
+
+// DeployQSFSBackends deploys qsfs zdbs on the given nodes and returns the qsfs using them, with the zdb deployments of each node.
+// if a zdb deployment fails, the ones deployed before are canceled
+func DeployQSFSBackends(ctx context.Context, t deployer.TFPluginClient, name string, nodes []uint32, zdbs []workloads.ZDB) (workloads.QSFS, map[uint32]uint64, error) {
+	qsfsName := fmt.Sprintf("%sqsfs", name)
+	deploymentName := fmt.Sprintf("%szdbs", qsfsName)
+
+	deployments := map[uint32]uint64{}
+	var dataBackends, metaBackends workloads.Backends
+	for _, node := range nodes {
+		dl := workloads.NewDeployment(deploymentName, node, name, nil, "", nil, zdbs, nil, nil)
+
+		log.Info().Msgf("deploying qsfs zdbs on node %d", node)
+		err := t.DeploymentDeployer.Deploy(ctx, &dl)
+		if dl.ContractID != 0 {
+			deployments[node] = dl.ContractID
+		}
+		if err != nil {
+			return workloads.QSFS{}, nil, CancelQSFSBackends(t, deployments, errors.Wrapf(err, "failed to deploy qsfs zdbs on node %d", node))
+		}
+		for _, zdb := range zdbs {
+			resZDB, err := t.State.LoadZdbFromGrid(node, zdb.Name, dl.Name)
+			if err != nil {
+				return workloads.QSFS{}, nil, CancelQSFSBackends(t, deployments, errors.Wrapf(err, "failed to load zdb %s from node %d", zdb.Name, node))
+			}
+			address, err := zdbBackendAddress(resZDB)
+			if err != nil {
+				return workloads.QSFS{}, nil, CancelQSFSBackends(t, deployments, errors.Wrapf(err, "failed to get zdb %s address on node %d", zdb.Name, node))
+			}
+			backend := workloads.Backend{
+				Address:   address,
+				Namespace: resZDB.Namespace,
+				Password:  resZDB.Password,
+			}
+			if resZDB.Mode == zos.ZDBModeSeq {
+				dataBackends = append(dataBackends, backend)
+			} else {
+				metaBackends = append(metaBackends, backend)
+			}
+		}
+	}
+
+	key, err := randomHex(qsfsEncryptionKeyLen)
+	if err != nil {
+		return workloads.QSFS{}, nil, CancelQSFSBackends(t, deployments, errors.Wrap(err, "failed to generate qsfs encryption key"))
+	}
+	return workloads.QSFS{
+		Name:                 qsfsName,
+		Cache:                QSFSCacheSize,
+		MinimalShards:        qsfsMinimalShards,
+		ExpectedShards:       uint32(len(dataBackends)),
+		MaxZDBDataDirSize:    qsfsMaxZDBDataDir,
+		EncryptionAlgorithm:  qsfsEncryption,
+		EncryptionKey:        key,
+		CompressionAlgorithm: qsfsCompression,
+		Metadata: workloads.Metadata{
+			Type:                qsfsMetadataType,
+			Prefix:              name,
+			EncryptionAlgorithm: qsfsEncryption,
+			EncryptionKey:       key,
+			Backends:            metaBackends,
+		},
+		Groups: workloads.Groups{{Backends: dataBackends}},
+	}, deployments, nil
+}
+
+// CancelQSFSBackends cancels the zdb deployments of a qsfs when it or its vm failed to deploy, and returns the error it failed with
+func CancelQSFSBackends(t deployer.TFPluginClient, deployments map[uint32]uint64, err error) error {
+	var remaining []uint64
+	for node, contractID := range deployments {
+		log.Info().Msgf("canceling qsfs zdbs deployment %d on node %d", contractID, node)
+		if cerr := t.SubstrateConn.CancelContract(t.Identity, contractID); cerr != nil {
+			log.Error().Err(cerr).Msgf("failed to cancel deployment %d", contractID)
+			remaining = append(remaining, contractID)
+		}
+	}
+	if len(remaining) != 0 {
+		return errkind.Wrap(errkind.PartialFailure, errors.Wrapf(err, "failed to cancel qsfs zdbs deployments %v, cancel them manually", remaining))
+	}
+	return err
+}
+
+// zdbBackendAddress returns the zdb address reachable from other nodes
+// yggdrasil ip is preferred since it is reachable from any node
+func zdbBackendAddress(zdb workloads.ZDB) (string, error) {
+	var address net.IP
+	for _, ipStr := range zdb.IPs {
+		ip := net.ParseIP(ipStr)
+		if ip == nil || ip.To4() != nil {
+			continue
+		}
+		if isYggdrasilIP(ip) {
+			address = ip
+			break
+		}
+		if address == nil && ip.IsGlobalUnicast() {
+			address = ip
+		}
+	}
+	if address == nil {
+		return "", fmt.Errorf("no reachable ipv6 found in zdb ips %v", zdb.IPs)
+	}
+	return fmt.Sprintf("[%s]:%d", address, zdb.Port), nil
+}
+
+func isYggdrasilIP(ip net.IP) bool {
+	yggNet := net.IPNet{
+		IP:   net.ParseIP("200::"),
+		Mask: net.CIDRMask(7, 128),
+	}
+	return yggNet.Contains(ip)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
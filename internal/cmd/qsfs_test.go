@@ -0,0 +1,72 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+)
+
+func TestBuildQSFSZDBs(t *testing.T) {
+	zdbs, err := BuildQSFSZDBs("test", 11)
+	assert.NoError(t, err)
+	assert.Len(t, zdbs, 2)
+
+	assert.Equal(t, zdbs[0].Name, "testdata")
+	assert.Equal(t, zdbs[0].Mode, "seq")
+	assert.Equal(t, zdbs[0].Size, 6)
+
+	assert.Equal(t, zdbs[1].Name, "testmeta")
+	assert.Equal(t, zdbs[1].Mode, "user")
+	assert.Equal(t, zdbs[1].Size, 1)
+}
+
+func TestZDBBackendAddress(t *testing.T) {
+	t.Run("yggdrasil ip is preferred", func(t *testing.T) {
+		zdb := workloads.ZDB{
+			IPs:  []string{"2a02:1802:5e:0:1000::1", "302:9e63:7d43:b742::1"},
+			Port: 9900,
+		}
+		address, err := zdbBackendAddress(zdb)
+		assert.NoError(t, err)
+		assert.Equal(t, address, "[302:9e63:7d43:b742::1]:9900")
+	})
+	t.Run("public ipv6 is used if no yggdrasil ip", func(t *testing.T) {
+		zdb := workloads.ZDB{
+			IPs:  []string{"10.20.2.2", "2a02:1802:5e:0:1000::1"},
+			Port: 9900,
+		}
+		address, err := zdbBackendAddress(zdb)
+		assert.NoError(t, err)
+		assert.Equal(t, address, "[2a02:1802:5e:0:1000::1]:9900")
+	})
+	t.Run("no reachable ip", func(t *testing.T) {
+		zdb := workloads.ZDB{
+			IPs:  []string{"10.20.2.2"},
+			Port: 9900,
+		}
+		_, err := zdbBackendAddress(zdb)
+		assert.Error(t, err)
+	})
+}
+
+func TestCancelQSFSBackends(t *testing.T) {
+	deployErr := errors.New("failed to deploy vm")
+
+	sub := &fakeSubstrate{}
+	err := CancelQSFSBackends(deployer.TFPluginClient{SubstrateConn: sub}, map[uint32]uint64{11: 5, 12: 6}, deployErr)
+	assert.Equal(t, deployErr, err)
+	assert.ElementsMatch(t, []uint64{5, 6}, sub.canceled)
+
+	sub = &fakeSubstrate{failOn: 6}
+	err = CancelQSFSBackends(deployer.TFPluginClient{SubstrateConn: sub}, map[uint32]uint64{11: 5, 12: 6}, deployErr)
+	assert.ErrorIs(t, err, deployErr)
+	assert.Equal(t, errkind.PartialFailure, errkind.Of(err))
+	assert.Equal(t, []uint64{5}, sub.canceled)
+
+	assert.Equal(t, deployErr, CancelQSFSBackends(deployer.TFPluginClient{}, nil, deployErr))
+}
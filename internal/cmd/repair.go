@@ -0,0 +1,223 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// RepairProject redeploys the workloads in error of the vm, kubernetes and gateway deployments of a project
+// the failed workloads, and the vms using them, are removed from their deployment then added back so the node installs them again,
+// healthy workloads are left untouched. it returns the redeployed workloads, and the failed workloads it can't repair
+func RepairProject(ctx context.Context, t deployer.TFPluginClient, projectName string) (repaired []WorkloadState, skipped []WorkloadState, err error) {
+	project, err := GetProject(ctx, t, projectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, dl := range project.Deployments {
+		failed := FailedWorkloads(deploymentStates(dl))
+		if len(failed) == 0 {
+			continue
+		}
+		if dl.Error != "" {
+			skipped = append(skipped, failed...)
+			continue
+		}
+		var redeployed []WorkloadState
+		switch dl.Type {
+		case "vm":
+			redeployed, err = repairDeployment(ctx, t, dl, failed)
+		case "kubernetes", "Gateway Name", "Gateway Fqdn":
+			redeployed, err = repairNodeDeployment(ctx, t, dl, failed)
+		default:
+			log.Warn().Msgf("deployment %s on node %d is a %s deployment, which can't be repaired", dl.Name, dl.NodeID, dl.Type)
+			skipped = append(skipped, failed...)
+			continue
+		}
+		if err != nil {
+			return repaired, skipped, errors.Wrapf(err, "failed to repair deployment %s on node %d", dl.Name, dl.NodeID)
+		}
+		repaired = append(repaired, redeployed...)
+	}
+	return repaired, skipped, nil
+}
+
+func repairDeployment(ctx context.Context, t deployer.TFPluginClient, deployment ProjectDeployment, failed []WorkloadState) ([]WorkloadState, error) {
+	zosDeployment, err := getNodeDeployment(ctx, t, deployment.NodeID, deployment.ContractID)
+	if err != nil {
+		return nil, err
+	}
+	// failed workloads may have no result data, which can't be loaded
+	for i := range zosDeployment.Workloads {
+		if len(zosDeployment.Workloads[i].Result.Data) == 0 {
+			zosDeployment.Workloads[i].Result.Data = json.RawMessage("null")
+		}
+	}
+	dl, err := workloads.NewDeploymentFromZosDeployment(zosDeployment, deployment.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	t.State.CurrentNodeDeployments[deployment.NodeID] = []uint64{deployment.ContractID}
+
+	failedNames := map[string]bool{}
+	for _, state := range failed {
+		failedNames[state.Name] = true
+	}
+	healthy, removed := withoutWorkloads(dl, failedNames)
+	if len(removed) == 0 {
+		return nil, errors.New("no failed workload can be redeployed")
+	}
+	for _, vm := range dl.Vms {
+		if removed[vm.Name] && (vm.PublicIP || vm.PublicIP6) {
+			log.Warn().Msgf("vm %s is recreated, its root filesystem is reset and its public ips may change", vm.Name)
+		} else if removed[vm.Name] {
+			log.Warn().Msgf("vm %s is recreated, its root filesystem is reset", vm.Name)
+		}
+	}
+
+	// vms keep their private ips, so the node subnet must be known to the deployer
+	if dl.NetworkName != "" {
+		network, err := LoadNetwork(ctx, t, dl.NetworkName)
+		if err != nil {
+			return nil, err
+		}
+		t.State.GetNetworks().UpdateNetwork(dl.NetworkName, network.NodesIPRange)
+	}
+
+	// the failed workloads are deployed again if their first redeploy fails, so they are not left removed
+	if err := replaceWorkloads(ctx, t.DeploymentDeployer.Deploy, healthy, dl, dl); err != nil {
+		return nil, errors.Wrap(err, "failed to redeploy workloads")
+	}
+
+	var redeployed []WorkloadState
+	for _, state := range deploymentStates(deployment) {
+		if removed[state.Name] {
+			redeployed = append(redeployed, state)
+		}
+	}
+	return redeployed, nil
+}
+
+// repairNodeDeployment redeploys the failed workloads of a kubernetes or gateway deployment, and the zmachines using them,
+// by removing them from the node deployment then adding them back
+func repairNodeDeployment(ctx context.Context, t deployer.TFPluginClient, deployment ProjectDeployment, failed []WorkloadState) ([]WorkloadState, error) {
+	dl, err := getNodeDeployment(ctx, t, deployment.NodeID, deployment.ContractID)
+	if err != nil {
+		return nil, err
+	}
+	failedNames := map[string]bool{}
+	for _, state := range failed {
+		failedNames[state.Name] = true
+	}
+	names, err := withUsingZMachines(dl, failedNames)
+	if err != nil {
+		return nil, err
+	}
+	for _, wl := range dl.Workloads {
+		if names[string(wl.Name)] && wl.Type == zos.ZMachineType {
+			log.Warn().Msgf("%s is recreated, its root filesystem is reset", wl.Name)
+		}
+	}
+
+	if err := recreateWorkloads(ctx, t, deployment.NodeID, dl, dl, names); err != nil {
+		return nil, errors.Wrap(err, "failed to redeploy workloads")
+	}
+
+	var redeployed []WorkloadState
+	for _, state := range deploymentStates(deployment) {
+		if names[state.Name] {
+			redeployed = append(redeployed, state)
+		}
+	}
+	return redeployed, nil
+}
+
+// withUsingZMachines returns the given workload names with the zmachines mounting or using one of them added,
+// as a zmachine can't be kept in a deployment without the workloads it uses
+func withUsingZMachines(dl gridtypes.Deployment, names map[string]bool) (map[string]bool, error) {
+	res := map[string]bool{}
+	for name := range names {
+		res[name] = true
+	}
+	for _, wl := range dl.Workloads {
+		if wl.Type != zos.ZMachineType || res[string(wl.Name)] {
+			continue
+		}
+		data, err := wl.WorkloadData()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load zmachine %s data", wl.Name)
+		}
+		machine, ok := data.(*zos.ZMachine)
+		if !ok {
+			return nil, errors.Errorf("workload %s is not a zmachine", wl.Name)
+		}
+		uses := names[string(machine.Network.PublicIP)]
+		for _, mount := range machine.Mounts {
+			uses = uses || names[string(mount.Name)]
+		}
+		if uses {
+			res[string(wl.Name)] = true
+		}
+	}
+	return res, nil
+}
+
+func deploymentStates(deployment ProjectDeployment) []WorkloadState {
+	return Project{Deployments: []ProjectDeployment{deployment}}.WorkloadStates()
+}
+
+// withoutWorkloads returns a copy of a deployment without the given workloads, and the names of the removed workloads
+// vms whose public ip or mounted disks are removed are removed too, as they can't be installed again without them
+func withoutWorkloads(dl workloads.Deployment, names map[string]bool) (workloads.Deployment, map[string]bool) {
+	removed := map[string]bool{}
+	res := dl
+	res.Disks = nil
+	for _, disk := range dl.Disks {
+		if names[disk.Name] {
+			removed[disk.Name] = true
+			continue
+		}
+		res.Disks = append(res.Disks, disk)
+	}
+	res.Zdbs = nil
+	for _, zdb := range dl.Zdbs {
+		if names[zdb.Name] {
+			removed[zdb.Name] = true
+			continue
+		}
+		res.Zdbs = append(res.Zdbs, zdb)
+	}
+	res.QSFS = nil
+	for _, qsfs := range dl.QSFS {
+		if names[qsfs.Name] {
+			removed[qsfs.Name] = true
+			continue
+		}
+		res.QSFS = append(res.QSFS, qsfs)
+	}
+	res.Vms = nil
+	for _, vm := range dl.Vms {
+		publicIP := fmt.Sprintf("%sip", vm.Name)
+		remove := names[vm.Name] || names[publicIP]
+		for _, mount := range vm.Mounts {
+			remove = remove || removed[mount.DiskName]
+		}
+		if remove {
+			removed[vm.Name] = true
+			if vm.PublicIP || vm.PublicIP6 {
+				removed[publicIP] = true
+			}
+			continue
+		}
+		res.Vms = append(res.Vms, vm)
+	}
+	return res, removed
+}
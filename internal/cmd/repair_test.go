@@ -0,0 +1,63 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestWithoutWorkloads(t *testing.T) {
+	dl := workloads.Deployment{
+		Name: "vm",
+		Vms: []workloads.VM{
+			{Name: "vm", PublicIP: true, Mounts: []workloads.Mount{{DiskName: "vmdisk", MountPoint: "/data"}}},
+			{Name: "other"},
+			{Name: "withip", PublicIP: true},
+		},
+		Disks: []workloads.Disk{{Name: "vmdisk", SizeGB: 10}, {Name: "spare", SizeGB: 5}},
+		Zdbs:  []workloads.ZDB{{Name: "zdb"}},
+	}
+
+	healthy, removed := withoutWorkloads(dl, map[string]bool{"vmdisk": true, "withipip": true})
+	assert.Equal(t, map[string]bool{"vmdisk": true, "vm": true, "vmip": true, "withip": true, "withipip": true}, removed)
+	assert.Equal(t, []workloads.VM{{Name: "other"}}, healthy.Vms)
+	assert.Equal(t, []workloads.Disk{{Name: "spare", SizeGB: 5}}, healthy.Disks)
+	assert.Equal(t, dl.Zdbs, healthy.Zdbs)
+	assert.Len(t, dl.Vms, 3)
+
+	healthy, removed = withoutWorkloads(dl, map[string]bool{"zdb": true})
+	assert.Equal(t, map[string]bool{"zdb": true}, removed)
+	assert.Empty(t, healthy.Zdbs)
+	assert.Equal(t, dl.Vms, healthy.Vms)
+}
+
+func TestWithUsingZMachines(t *testing.T) {
+	dl := gridtypes.Deployment{
+		Workloads: []gridtypes.Workload{
+			{Name: "masterdisk", Type: zos.ZMountType, Data: gridtypes.MustMarshal(zos.ZMount{Size: gridtypes.Gigabyte})},
+			{Name: "master", Type: zos.ZMachineType, Data: gridtypes.MustMarshal(zos.ZMachine{
+				Mounts: []zos.MachineMount{{Name: "masterdisk", Mountpoint: "/data"}},
+			})},
+			{Name: "workerip", Type: zos.PublicIPType, Data: gridtypes.MustMarshal(zos.PublicIP{V4: true})},
+			{Name: "worker", Type: zos.ZMachineType, Data: gridtypes.MustMarshal(zos.ZMachine{
+				Network: zos.MachineNetwork{PublicIP: "workerip"},
+			})},
+		},
+	}
+
+	names, err := withUsingZMachines(dl, map[string]bool{"masterdisk": true})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"masterdisk": true, "master": true}, names)
+
+	names, err = withUsingZMachines(dl, map[string]bool{"workerip": true, "master": true})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"workerip": true, "worker": true, "master": true}, names)
+
+	names, err = withUsingZMachines(dl, map[string]bool{"worker": true})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"worker": true}, names)
+}
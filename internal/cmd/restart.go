@@ -0,0 +1,183 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// RestartVM restarts a vm by recreating it from the same spec, its disks and public ips are kept
+func RestartVM(ctx context.Context, t deployer.TFPluginClient, name string) (workloads.VM, error) {
+	restarted, err := restartZMachines(ctx, t, name, "vm", name)
+	if err != nil {
+		return workloads.VM{}, err
+	}
+	if restarted == 0 {
+		return workloads.VM{}, errkind.Errorf(errkind.NotFound, "no vm with name %s found", name)
+	}
+	vm, err := getVMWorkload(t, name)
+	if err != nil {
+		return workloads.VM{}, err
+	}
+	ForgetSSHHosts(vm.ComputedIP, vm.ComputedIP6, vm.YggIP, vm.IP)
+	return vm, nil
+}
+
+// RestartK8sCluster restarts the nodes of a kubernetes cluster by recreating them from the same spec,
+// their disks and public ips are kept. only the node named nodeName is restarted if it is set
+func RestartK8sCluster(ctx context.Context, t deployer.TFPluginClient, name, nodeName string) (workloads.K8sCluster, error) {
+	restarted, err := restartZMachines(ctx, t, name, "kubernetes", nodeName)
+	if err != nil {
+		return workloads.K8sCluster{}, err
+	}
+	if restarted == 0 && nodeName != "" {
+		return workloads.K8sCluster{}, errkind.Errorf(errkind.NotFound, "no kubernetes node with name %s found in cluster %s", nodeName, name)
+	}
+	if restarted == 0 {
+		return workloads.K8sCluster{}, errkind.Errorf(errkind.NotFound, "no k8s cluster with name %s found", name)
+	}
+	cluster, err := GetK8sCluster(t, name)
+	if err != nil {
+		return workloads.K8sCluster{}, err
+	}
+	for _, node := range append([]workloads.K8sNode{*cluster.Master}, cluster.Workers...) {
+		if nodeName == "" || node.Name == nodeName {
+			ForgetSSHHosts(node.ComputedIP, node.ComputedIP6, node.YggIP, node.IP)
+		}
+	}
+	return cluster, nil
+}
+
+// getVMWorkload gets the vm of a vm project
+func getVMWorkload(t deployer.TFPluginClient, name string) (workloads.VM, error) {
+	dl, err := GetVM(t, name)
+	if err != nil {
+		return workloads.VM{}, err
+	}
+	for _, vm := range dl.Vms {
+		if vm.Name == name {
+			return vm, nil
+		}
+	}
+	return workloads.VM{}, errkind.Errorf(errkind.NotFound, "no vm with name %s found in deployment %s", name, dl.Name)
+}
+
+// restartZMachines recreates the zmachines of the project deployments with the given type,
+// or only the zmachine named machine if it is set. it returns the number of restarted zmachines
+func restartZMachines(ctx context.Context, t deployer.TFPluginClient, projectName, deploymentType, machine string) (int, error) {
+	contracts, _, err := listProjectContracts(t, projectName)
+	if err != nil {
+		return 0, err
+	}
+	restarted := 0
+	for _, contract := range contracts {
+		if contract.deploymentType != deploymentType || contract.name != projectName {
+			continue
+		}
+		dl, err := getNodeDeployment(ctx, t, contract.nodeID, contract.contractID)
+		if err != nil {
+			return restarted, err
+		}
+		names := map[string]bool{}
+		for _, wl := range dl.Workloads {
+			if wl.Type == zos.ZMachineType && (machine == "" || string(wl.Name) == machine) {
+				names[string(wl.Name)] = true
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		err = recreateWorkloads(ctx, t, contract.nodeID, dl, dl, names)
+		if err != nil {
+			return restarted, errors.Wrapf(err, "failed to restart deployment %s on node %d", contract.name, contract.nodeID)
+		}
+		restarted += len(names)
+	}
+	return restarted, nil
+}
+
+// recreateWorkloads removes workloads from a deployment then adds them back from dl with a new version,
+// so the node installs them again. if adding them fails, they are added back from previous,
+// which is dl itself to retry the same spec. other workloads are left untouched.
+// it is not stopped by interrupts, which would leave the workloads removed
+func recreateWorkloads(ctx context.Context, t deployer.TFPluginClient, nodeID uint32, dl, previous gridtypes.Deployment, names map[string]bool) error {
+	d := deployer.NewDeployer(t, true)
+	return recreateNodeWorkloads(ctx, d.Deploy, nodeID, dl, previous, names)
+}
+
+// zosDeployFunc deploys node deployments, like the grid deployer does
+type zosDeployFunc func(ctx context.Context, oldDeployments map[uint32]uint64, newDeployments map[uint32]gridtypes.Deployment, solutionProviders map[uint32]*uint64) (map[uint32]uint64, error)
+
+func recreateNodeWorkloads(ctx context.Context, deploy zosDeployFunc, nodeID uint32, dl, previous gridtypes.Deployment, names map[string]bool) error {
+	ctx = uninterruptible{ctx}
+	without, recreated := splitWorkloads(dl, names)
+	_, previous = splitWorkloads(previous, names)
+	oldDeployments := map[uint32]uint64{nodeID: dl.ContractID}
+
+	log.Info().Msgf("removing %d workloads from deployment %d on node %d", len(names), dl.ContractID, nodeID)
+	_, err := deploy(ctx, oldDeployments, map[uint32]gridtypes.Deployment{nodeID: without}, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove workloads")
+	}
+	log.Info().Msgf("adding back %d workloads to deployment %d on node %d", len(names), dl.ContractID, nodeID)
+	_, err = deploy(ctx, oldDeployments, map[uint32]gridtypes.Deployment{nodeID: recreated}, nil)
+	if err == nil {
+		return nil
+	}
+	log.Error().Err(err).Msg("failed to add back workloads, adding back the previous ones")
+	_, rerr := deploy(ctx, oldDeployments, map[uint32]gridtypes.Deployment{nodeID: previous}, nil)
+	if rerr != nil {
+		return errkind.Wrap(errkind.PartialFailure, errors.Wrapf(err, "workloads %v are removed from deployment %d on node %d, adding them back failed again: %s", sortedNames(names), dl.ContractID, nodeID, rerr))
+	}
+	if workloadsEqual(recreated, previous) {
+		return nil
+	}
+	return errors.Wrap(err, "the previous workloads were added back")
+}
+
+// workloadsEqual checks two deployments have the same workloads data
+func workloadsEqual(a, b gridtypes.Deployment) bool {
+	if len(a.Workloads) != len(b.Workloads) {
+		return false
+	}
+	for i := range a.Workloads {
+		if a.Workloads[i].Name != b.Workloads[i].Name || string(a.Workloads[i].Data) != string(b.Workloads[i].Data) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// splitWorkloads returns a copy of a deployment without the named workloads,
+// and a copy with their node results cleared so they are installed as new workloads
+func splitWorkloads(dl gridtypes.Deployment, names map[string]bool) (without gridtypes.Deployment, recreated gridtypes.Deployment) {
+	without, recreated = dl, dl
+	without.Workloads = nil
+	recreated.Workloads = make([]gridtypes.Workload, 0, len(dl.Workloads))
+	for _, wl := range dl.Workloads {
+		if names[string(wl.Name)] {
+			wl.Result = gridtypes.Result{}
+		} else {
+			without.Workloads = append(without.Workloads, wl)
+		}
+		recreated.Workloads = append(recreated.Workloads, wl)
+	}
+	return without, recreated
+}
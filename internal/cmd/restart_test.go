@@ -0,0 +1,34 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestSplitWorkloads(t *testing.T) {
+	ok := gridtypes.Result{State: gridtypes.StateOk}
+	dl := gridtypes.Deployment{
+		Version:    2,
+		ContractID: 20,
+		Workloads: []gridtypes.Workload{
+			{Name: "vmdisk", Type: zos.ZMountType, Version: 0, Result: ok},
+			{Name: "vmip", Type: zos.PublicIPType, Version: 0, Result: ok},
+			{Name: "vm", Type: zos.ZMachineType, Version: 1, Result: ok},
+		},
+	}
+
+	without, recreated := splitWorkloads(dl, map[string]bool{"vm": true})
+	assert.Len(t, without.Workloads, 2)
+	assert.Equal(t, dl.Workloads[:2], without.Workloads)
+	assert.Equal(t, uint64(20), without.ContractID)
+
+	assert.Len(t, recreated.Workloads, 3)
+	assert.Equal(t, dl.Workloads[:2], recreated.Workloads[:2])
+	assert.Equal(t, gridtypes.Name("vm"), recreated.Workloads[2].Name)
+	assert.Equal(t, gridtypes.Result{}, recreated.Workloads[2].Result)
+	assert.Equal(t, ok, dl.Workloads[2].Result)
+}
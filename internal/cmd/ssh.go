@@ -0,0 +1,238 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// SSHTarget is a deployed vm or kubernetes node to connect to with ssh
+type SSHTarget struct {
+	Name      string
+	Address   string
+	YggIP     string
+	PublicKey string
+}
+
+// GetSSHTarget resolves the address and the deployed ssh key of a vm or a kubernetes node of a project
+// nodeName selects a kubernetes node, the master is used if it is empty
+func GetSSHTarget(t deployer.TFPluginClient, projectName, nodeName string) (SSHTarget, error) {
+	deploymentType, err := getProjectType(t, projectName)
+	if err != nil {
+		return SSHTarget{}, err
+	}
+	if deploymentType == "vm" {
+		dl, err := GetVM(t, projectName)
+		if err != nil {
+			return SSHTarget{}, err
+		}
+		if nodeName == "" {
+			nodeName = projectName
+		}
+		for _, vm := range dl.Vms {
+			if vm.Name != nodeName {
+				continue
+			}
+			address, err := sshAddress(vm.ComputedIP, vm.ComputedIP6, vm.YggIP, vm.IP)
+			if err != nil {
+				return SSHTarget{}, errors.Wrapf(err, "vm %s is not reachable", vm.Name)
+			}
+			return SSHTarget{Name: vm.Name, Address: address, YggIP: vm.YggIP, PublicKey: vm.EnvVars["SSH_KEY"]}, nil
+		}
+		return SSHTarget{}, errkind.Errorf(errkind.NotFound, "no vm with name %s found in project %s", nodeName, projectName)
+	}
+
+	cluster, err := GetK8sCluster(t, projectName)
+	if err != nil {
+		return SSHTarget{}, err
+	}
+	nodes := append([]workloads.K8sNode{*cluster.Master}, cluster.Workers...)
+	if nodeName == "" {
+		nodeName = cluster.Master.Name
+	}
+	for _, node := range nodes {
+		if node.Name != nodeName {
+			continue
+		}
+		address, err := sshAddress(node.ComputedIP, node.ComputedIP6, node.YggIP, node.IP)
+		if err != nil {
+			return SSHTarget{}, errors.Wrapf(err, "kubernetes node %s is not reachable", node.Name)
+		}
+		// ssh key is not loaded with the cluster, it is read from the node environment
+		key, err := getZMachineEnv(t, node.Node, node.Name, projectName, "SSH_KEY")
+		if err != nil {
+			return SSHTarget{}, err
+		}
+		return SSHTarget{Name: node.Name, Address: address, YggIP: node.YggIP, PublicKey: key}, nil
+	}
+	return SSHTarget{}, errkind.Errorf(errkind.NotFound, "no kubernetes node with name %s found in project %s", nodeName, projectName)
+}
+
+// knownHostsFile keeps the host keys of deployments apart from the user known hosts,
+// as deployments often reuse the ips of removed ones
+const knownHostsFile = ".tfgrid_known_hosts"
+
+// SSHArgs returns the system ssh client arguments to connect to a target and run an optional command
+// the key of a new host is accepted and saved in the tf-grid known hosts file
+func SSHArgs(target SSHTarget, identity, user string, command ...string) []string {
+	args := []string{"-o", "StrictHostKeyChecking=accept-new"}
+	if path, err := knownHostsPath(); err == nil {
+		args = append(args, "-o", fmt.Sprintf("UserKnownHostsFile=%s", path))
+	}
+	args = append(args, "-i", identity, fmt.Sprintf("%s@%s", user, target.Address))
+	return append(args, command...)
+}
+
+// SSHOutput runs a command on a target with the system ssh client and returns its output
+func SSHOutput(target SSHTarget, identity, user string, command ...string) ([]byte, error) {
+	args := append([]string{"-o", "BatchMode=yes"}, SSHArgs(target, identity, user, command...)...)
+	out, err := exec.Command("ssh", args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		stderr := strings.TrimSpace(string(exitErr.Stderr))
+		if strings.Contains(stderr, "REMOTE HOST IDENTIFICATION HAS CHANGED") {
+			path, _ := knownHostsPath()
+			return nil, fmt.Errorf("host key of %s changed, if it was redeployed remove its old key with: ssh-keygen -f %s -R %s", target.Address, path, target.Address)
+		}
+		return nil, fmt.Errorf("ssh command failed: %s", stderr)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not run ssh")
+	}
+	return out, nil
+}
+
+// ForgetSSHHosts removes the saved host keys of ips from the tf-grid known hosts file,
+// it is used when a host is recreated with new keys. failures are ignored
+func ForgetSSHHosts(ips ...string) {
+	path, err := knownHostsPath()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	for _, ip := range ips {
+		if address := configIP(ip); address != "" {
+			_ = exec.Command("ssh-keygen", "-f", path, "-R", address).Run()
+		}
+	}
+}
+
+func knownHostsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, knownHostsFile), nil
+}
+
+// FindSSHIdentity returns the path of the private key in ~/.ssh matching a public key
+func FindSSHIdentity(publicKey string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "could not get home directory")
+	}
+	return findSSHIdentity(filepath.Join(home, ".ssh"), publicKey)
+}
+
+func findSSHIdentity(dir, publicKey string) (string, error) {
+	wanted := sshKeyID(publicKey)
+	if wanted == "" {
+		return "", errors.New("deployment has no ssh key")
+	}
+	pubFiles, err := filepath.Glob(filepath.Join(dir, "*.pub"))
+	if err != nil {
+		return "", err
+	}
+	for _, pubFile := range pubFiles {
+		content, err := os.ReadFile(pubFile)
+		if err != nil {
+			continue
+		}
+		if sshKeyID(string(content)) != wanted {
+			continue
+		}
+		privateFile := strings.TrimSuffix(pubFile, ".pub")
+		if _, err := os.Stat(privateFile); err != nil {
+			continue
+		}
+		return privateFile, nil
+	}
+	return "", errkind.Errorf(errkind.Config, "no private key matching the deployed ssh key found in %s", dir)
+}
+
+// sshKeyID returns the type and base64 data of an authorized key, ignoring its comment
+func sshKeyID(key string) string {
+	fields := strings.Fields(key)
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[0] + " " + fields[1]
+}
+
+// sshAddress picks the address to connect to in order of preference:
+// public ipv4, public ipv6, yggdrasil ip then private ip through wireguard
+func sshAddress(ipv4, ipv6, ygg, private string) (string, error) {
+	for _, address := range []string{ipv4, ipv6, ygg, private} {
+		if address == "" {
+			continue
+		}
+		ip, _, err := net.ParseCIDR(address)
+		if err != nil {
+			ip = net.ParseIP(address)
+		}
+		if ip != nil {
+			return ip.String(), nil
+		}
+	}
+	return "", errors.New("no ip found")
+}
+
+// getProjectType returns whether a project is a vm or a kubernetes deployment
+func getProjectType(t deployer.TFPluginClient, projectName string) (string, error) {
+	contracts, err := t.ContractsGetter.ListContractsOfProjectName(projectName)
+	if err != nil {
+		return "", err
+	}
+	for _, contract := range contracts.NodeContracts {
+		var deploymentData workloads.DeploymentData
+		err := json.Unmarshal([]byte(contract.DeploymentData), &deploymentData)
+		if err != nil {
+			return "", err
+		}
+		if deploymentData.Name != projectName {
+			continue
+		}
+		if deploymentData.Type == "vm" || deploymentData.Type == "kubernetes" {
+			return deploymentData.Type, nil
+		}
+	}
+	return "", errkind.Errorf(errkind.NotFound, "no vm or kubernetes cluster with name %s found", projectName)
+}
+
+func getZMachineEnv(t deployer.TFPluginClient, nodeID uint32, name, deploymentName, key string) (string, error) {
+	wl, _, err := t.State.GetWorkloadInDeployment(nodeID, name, deploymentName)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not get workload %s from node %d", name, nodeID)
+	}
+	dataI, err := wl.WorkloadData()
+	if err != nil {
+		return "", errors.Wrapf(err, "could not get workload %s data", name)
+	}
+	data, ok := dataI.(*zos.ZMachine)
+	if !ok {
+		return "", fmt.Errorf("could not create vm workload from data %v", dataI)
+	}
+	return data.Env[key], nil
+}
@@ -0,0 +1,61 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHAddress(t *testing.T) {
+	address, err := sshAddress("185.206.122.33/24", "2a10:b600::1/64", "300:e9c4::1", "10.20.2.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "185.206.122.33", address)
+
+	address, err = sshAddress("", "2a10:b600::1/64", "300:e9c4::1", "10.20.2.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "2a10:b600::1", address)
+
+	address, err = sshAddress("", "", "300:e9c4::1", "10.20.2.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "300:e9c4::1", address)
+
+	address, err = sshAddress("", "", "", "10.20.2.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.20.2.2", address)
+
+	_, err = sshAddress("", "", "", "")
+	assert.Error(t, err)
+}
+
+func TestFindSSHIdentity(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "id_rsa.pub"), []byte("ssh-rsa AAAAother user@host\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "id_rsa"), []byte("private"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "id_ed25519.pub"), []byte("ssh-ed25519 AAAAkey user@host\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "id_ed25519"), []byte("private"), 0600))
+
+	identity, err := findSSHIdentity(dir, "ssh-ed25519 AAAAkey deployed-comment")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "id_ed25519"), identity)
+
+	_, err = findSSHIdentity(dir, "ssh-ed25519 AAAAmissing")
+	assert.Error(t, err)
+}
+
+func TestSSHArgs(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("HOME", configDir)
+	path, err := knownHostsPath()
+	assert.NoError(t, err)
+
+	args := SSHArgs(SSHTarget{Address: "185.206.122.33"}, "/keys/id_ed25519", "root", "uptime")
+	assert.Equal(t, []string{
+		"-o", "StrictHostKeyChecking=accept-new",
+		"-o", "UserKnownHostsFile=" + path,
+		"-i", "/keys/id_ed25519", "root@185.206.122.33", "uptime",
+	}, args)
+}
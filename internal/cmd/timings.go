@@ -0,0 +1,139 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/grid3-go/deployer"
+	client "github.com/threefoldtech/grid3-go/node"
+	"github.com/threefoldtech/grid3-go/subi"
+	proxy "github.com/threefoldtech/grid_proxy_server/pkg/client"
+	"github.com/threefoldtech/grid_proxy_server/pkg/types"
+	"github.com/threefoldtech/rmb-sdk-go"
+	"github.com/threefoldtech/substrate-client"
+)
+
+// TimeCalls returns a copy of a grid client logging the duration of its rmb, substrate and grid proxy calls at debug level
+func TimeCalls(t deployer.TFPluginClient) deployer.TFPluginClient {
+	t.RMB = timedRMB{Client: t.RMB}
+	t.NcPool = client.NewNodeClientPool(t.RMB, t.RMBTimeout)
+	t.SubstrateConn = timedSubstrate{SubstrateExt: t.SubstrateConn}
+	t.GridProxyClient = timedProxy{Client: t.GridProxyClient}
+	rebuildDeployers(&t)
+	return t
+}
+
+// logCall logs the duration and error of a call started at start
+func logCall(service, call string, start time.Time, err *error) {
+	log.Debug().Err(*err).Dur("duration", time.Since(start)).Msgf("%s %s", service, call)
+}
+
+type timedRMB struct {
+	rmb.Client
+}
+
+func (r timedRMB) Call(ctx context.Context, twin uint32, fn string, data interface{}, result interface{}) (err error) {
+	defer logCall("rmb", fmt.Sprintf("%s to twin %d", fn, twin), time.Now(), &err)
+	return r.Client.Call(ctx, twin, fn, data, result)
+}
+
+type timedProxy struct {
+	proxy.Client
+}
+
+func (p timedProxy) Nodes(filter types.NodeFilter, pagination types.Limit) (res []types.Node, totalCount int, err error) {
+	defer logCall("proxy", "nodes", time.Now(), &err)
+	return p.Client.Nodes(filter, pagination)
+}
+
+func (p timedProxy) Farms(filter types.FarmFilter, pagination types.Limit) (res []types.Farm, totalCount int, err error) {
+	defer logCall("proxy", "farms", time.Now(), &err)
+	return p.Client.Farms(filter, pagination)
+}
+
+func (p timedProxy) Node(nodeID uint32) (res types.NodeWithNestedCapacity, err error) {
+	defer logCall("proxy", fmt.Sprintf("node %d", nodeID), time.Now(), &err)
+	return p.Client.Node(nodeID)
+}
+
+type timedSubstrate struct {
+	subi.SubstrateExt
+}
+
+func (s timedSubstrate) CancelContract(identity substrate.Identity, contractID uint64) (err error) {
+	defer logCall("substrate", fmt.Sprintf("cancel contract %d", contractID), time.Now(), &err)
+	return s.SubstrateExt.CancelContract(identity, contractID)
+}
+
+func (s timedSubstrate) CreateNodeContract(identity substrate.Identity, node uint32, body string, hash string, publicIPs uint32, solutionProviderID *uint64) (contractID uint64, err error) {
+	defer logCall("substrate", fmt.Sprintf("create node contract on node %d", node), time.Now(), &err)
+	return s.SubstrateExt.CreateNodeContract(identity, node, body, hash, publicIPs, solutionProviderID)
+}
+
+func (s timedSubstrate) UpdateNodeContract(identity substrate.Identity, contract uint64, body string, hash string) (contractID uint64, err error) {
+	defer logCall("substrate", fmt.Sprintf("update node contract %d", contract), time.Now(), &err)
+	return s.SubstrateExt.UpdateNodeContract(identity, contract, body, hash)
+}
+
+func (s timedSubstrate) GetTwinByPubKey(pk []byte) (twinID uint32, err error) {
+	defer logCall("substrate", "get twin by public key", time.Now(), &err)
+	return s.SubstrateExt.GetTwinByPubKey(pk)
+}
+
+func (s timedSubstrate) EnsureContractCanceled(identity substrate.Identity, contractID uint64) (err error) {
+	defer logCall("substrate", fmt.Sprintf("ensure contract %d canceled", contractID), time.Now(), &err)
+	return s.SubstrateExt.EnsureContractCanceled(identity, contractID)
+}
+
+func (s timedSubstrate) DeleteInvalidContracts(contracts map[uint32]uint64) (err error) {
+	defer logCall("substrate", "delete invalid contracts", time.Now(), &err)
+	return s.SubstrateExt.DeleteInvalidContracts(contracts)
+}
+
+func (s timedSubstrate) IsValidContract(contractID uint64) (valid bool, err error) {
+	defer logCall("substrate", fmt.Sprintf("check contract %d", contractID), time.Now(), &err)
+	return s.SubstrateExt.IsValidContract(contractID)
+}
+
+func (s timedSubstrate) InvalidateNameContract(ctx context.Context, identity substrate.Identity, contractID uint64, name string) (newContractID uint64, err error) {
+	defer logCall("substrate", fmt.Sprintf("invalidate name contract %d", contractID), time.Now(), &err)
+	return s.SubstrateExt.InvalidateNameContract(ctx, identity, contractID, name)
+}
+
+func (s timedSubstrate) GetContract(id uint64) (contract subi.Contract, err error) {
+	defer logCall("substrate", fmt.Sprintf("get contract %d", id), time.Now(), &err)
+	return s.SubstrateExt.GetContract(id)
+}
+
+func (s timedSubstrate) GetNodeTwin(id uint32) (twinID uint32, err error) {
+	defer logCall("substrate", fmt.Sprintf("get twin of node %d", id), time.Now(), &err)
+	return s.SubstrateExt.GetNodeTwin(id)
+}
+
+func (s timedSubstrate) CreateNameContract(identity substrate.Identity, name string) (contractID uint64, err error) {
+	defer logCall("substrate", fmt.Sprintf("create name contract %s", name), time.Now(), &err)
+	return s.SubstrateExt.CreateNameContract(identity, name)
+}
+
+func (s timedSubstrate) GetAccount(identity substrate.Identity) (account substrate.AccountInfo, err error) {
+	defer logCall("substrate", "get account", time.Now(), &err)
+	return s.SubstrateExt.GetAccount(identity)
+}
+
+func (s timedSubstrate) GetBalance(identity substrate.Identity) (balance substrate.Balance, err error) {
+	defer logCall("substrate", "get balance", time.Now(), &err)
+	return s.SubstrateExt.GetBalance(identity)
+}
+
+func (s timedSubstrate) GetTwinPK(twinID uint32) (pk []byte, err error) {
+	defer logCall("substrate", fmt.Sprintf("get public key of twin %d", twinID), time.Now(), &err)
+	return s.SubstrateExt.GetTwinPK(twinID)
+}
+
+func (s timedSubstrate) GetContractIDByNameRegistration(name string) (contractID uint64, err error) {
+	defer logCall("substrate", fmt.Sprintf("get contract of name %s", name), time.Now(), &err)
+	return s.SubstrateExt.GetContractIDByNameRegistration(name)
+}
@@ -0,0 +1,31 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimedSubstrate(t *testing.T) {
+	defer func(logger zerolog.Logger, level zerolog.Level) {
+		log.Logger = logger
+		zerolog.SetGlobalLevel(level)
+	}(log.Logger, zerolog.GlobalLevel())
+	var logs bytes.Buffer
+	log.Logger = zerolog.New(&logs)
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+
+	sub := timedSubstrate{SubstrateExt: &fakeSubstrate{failOn: 2}}
+	contractID, err := sub.CreateNodeContract(nil, 11, "", "", 0, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), contractID)
+	assert.Error(t, sub.CancelContract(nil, 2))
+
+	assert.Contains(t, logs.String(), `"message":"substrate create node contract on node 11"`)
+	assert.Contains(t, logs.String(), `"duration":`)
+	assert.Contains(t, logs.String(), `"error":"failed to cancel","duration":`)
+}
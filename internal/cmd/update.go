@@ -0,0 +1,262 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/grid3-go/deployer"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/grid_proxy_server/pkg/types"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// UpdateGatewayName updates the backends and tls passthrough of a deployed gateway name in the same contract
+// nil backends or tls are kept unchanged
+func UpdateGatewayName(ctx context.Context, t deployer.TFPluginClient, name string, backends []zos.Backend, tls *bool) (workloads.GatewayNameProxy, error) {
+	gateway, err := GetGatewayName(t, name)
+	if err != nil {
+		return workloads.GatewayNameProxy{}, err
+	}
+	if backends != nil {
+		gateway.Backends = backends
+	}
+	if tls != nil {
+		gateway.TLSPassthrough = *tls
+	}
+	if err := ValidateBackends(gateway.Backends, gateway.TLSPassthrough); err != nil {
+		return workloads.GatewayNameProxy{}, err
+	}
+
+	log.Info().Msg("updating gateway name")
+	err = updateGateway(ctx, t, gateway.NodeID, gateway.ContractID, gateway.Name, gateway.Backends, gateway.TLSPassthrough)
+	if err != nil {
+		return workloads.GatewayNameProxy{}, errors.Wrapf(err, "failed to update gateway on node %d", gateway.NodeID)
+	}
+	return t.State.LoadGatewayNameFromGrid(gateway.NodeID, gateway.Name, gateway.Name)
+}
+
+// UpdateGatewayFQDN updates the backends and tls passthrough of a deployed gateway fqdn in the same contract
+// nil backends or tls are kept unchanged
+func UpdateGatewayFQDN(ctx context.Context, t deployer.TFPluginClient, name string, backends []zos.Backend, tls *bool) (workloads.GatewayFQDNProxy, error) {
+	gateway, err := GetGatewayFQDN(t, name)
+	if err != nil {
+		return workloads.GatewayFQDNProxy{}, err
+	}
+	if backends != nil {
+		gateway.Backends = backends
+	}
+	if tls != nil {
+		gateway.TLSPassthrough = *tls
+	}
+	if err := ValidateBackends(gateway.Backends, gateway.TLSPassthrough); err != nil {
+		return workloads.GatewayFQDNProxy{}, err
+	}
+
+	log.Info().Msg("updating gateway fqdn")
+	err = updateGateway(ctx, t, gateway.NodeID, gateway.ContractID, gateway.Name, gateway.Backends, gateway.TLSPassthrough)
+	if err != nil {
+		return workloads.GatewayFQDNProxy{}, errors.Wrapf(err, "failed to update gateway on node %d", gateway.NodeID)
+	}
+	return gateway, nil
+}
+
+// updateGateway sets the backends and tls passthrough of a gateway workload. zos can't update gateways,
+// so it is removed then added back in the same contract, and the previous gateway is added back if that fails
+func updateGateway(ctx context.Context, t deployer.TFPluginClient, nodeID uint32, contractID uint64, name string, backends []zos.Backend, tls bool) error {
+	dl, err := getNodeDeployment(ctx, t, nodeID, contractID)
+	if err != nil {
+		return err
+	}
+	updated, err := setGatewayWorkload(dl, name, backends, tls)
+	if err != nil {
+		return err
+	}
+	return recreateWorkloads(ctx, t, nodeID, updated, dl, map[string]bool{name: true})
+}
+
+// setGatewayWorkload returns a copy of a deployment with the backends and tls passthrough of its gateway workload set
+func setGatewayWorkload(dl gridtypes.Deployment, name string, backends []zos.Backend, tls bool) (gridtypes.Deployment, error) {
+	updated := dl
+	updated.Workloads = append([]gridtypes.Workload{}, dl.Workloads...)
+	for i, wl := range updated.Workloads {
+		if string(wl.Name) != name {
+			continue
+		}
+		dataI, err := wl.WorkloadData()
+		if err != nil {
+			return gridtypes.Deployment{}, errors.Wrapf(err, "could not get workload %s data", wl.Name)
+		}
+		switch data := dataI.(type) {
+		case *zos.GatewayNameProxy:
+			data.Backends, data.TLSPassthrough = backends, tls
+		case *zos.GatewayFQDNProxy:
+			data.Backends, data.TLSPassthrough = backends, tls
+		default:
+			return gridtypes.Deployment{}, errkind.Errorf(errkind.Validation, "workload %s is not a gateway", name)
+		}
+		raw, err := json.Marshal(dataI)
+		if err != nil {
+			return gridtypes.Deployment{}, err
+		}
+		updated.Workloads[i].Data = raw
+		return updated, nil
+	}
+	return gridtypes.Deployment{}, errkind.Errorf(errkind.NotFound, "no gateway with name %s found in deployment %d", name, dl.ContractID)
+}
+
+// ResizeVM changes the cpu, memory in mb and data disk size in gb of a deployed vm, zero values are kept unchanged
+// zos can't update a running vm, so it is removed then deployed again with the new specs in the same contract
+// disks and private ip are kept, while the root filesystem is reset and public ips may change
+func ResizeVM(ctx context.Context, t deployer.TFPluginClient, name string, cpu, memory, diskSize int) (workloads.VM, error) {
+	dl, err := GetVM(t, name)
+	if err != nil {
+		return workloads.VM{}, err
+	}
+	vmIdx := -1
+	for i, vm := range dl.Vms {
+		if vm.Name == name {
+			vmIdx = i
+		}
+	}
+	if vmIdx == -1 {
+		return workloads.VM{}, errkind.Errorf(errkind.NotFound, "no vm with name %s found in deployment %s", name, dl.Name)
+	}
+	vm := dl.Vms[vmIdx]
+	diskIdx := -1
+	for i, disk := range dl.Disks {
+		if disk.Name == fmt.Sprintf("%sdisk", name) {
+			diskIdx = i
+		}
+	}
+
+	var extraMRU, extraSRU gridtypes.Unit
+	if memory != 0 && memory > vm.Memory {
+		extraMRU = gridtypes.Unit(memory-vm.Memory) * gridtypes.Megabyte
+	}
+	if diskSize != 0 {
+		if diskIdx == -1 {
+			return workloads.VM{}, errkind.Errorf(errkind.Validation, "vm %s has no disk to resize", name)
+		}
+		if diskSize < dl.Disks[diskIdx].SizeGB {
+			return workloads.VM{}, errkind.Errorf(errkind.Validation, "disk of vm %s can't be shrunk from %d gb to %d gb", name, dl.Disks[diskIdx].SizeGB, diskSize)
+		}
+		extraSRU = gridtypes.Unit(diskSize-dl.Disks[diskIdx].SizeGB) * gridtypes.Gigabyte
+	}
+	node, err := t.GridProxyClient.Node(dl.NodeID)
+	if err != nil {
+		return workloads.VM{}, errors.Wrapf(err, "could not get node %d data from the grid proxy", dl.NodeID)
+	}
+	if err := checkResizeCapacity(node, extraMRU, extraSRU); err != nil {
+		return workloads.VM{}, err
+	}
+
+	// the vm keeps its private ip, so the node subnet must be known to the deployer
+	network, err := LoadNetwork(ctx, t, dl.NetworkName)
+	if err != nil {
+		return workloads.VM{}, err
+	}
+	t.State.GetNetworks().UpdateNetwork(dl.NetworkName, network.NodesIPRange)
+
+	log.Warn().Msgf("vm %s is recreated, its root filesystem is reset and its public ips may change", name)
+	without := dl
+	without.Vms = append(append([]workloads.VM{}, dl.Vms[:vmIdx]...), dl.Vms[vmIdx+1:]...)
+	resized := dl
+	resized.Vms = append([]workloads.VM{}, dl.Vms...)
+	resized.Disks = append([]workloads.Disk{}, dl.Disks...)
+	if cpu != 0 {
+		resized.Vms[vmIdx].CPU = cpu
+	}
+	if memory != 0 {
+		resized.Vms[vmIdx].Memory = memory
+	}
+	if diskSize != 0 {
+		resized.Disks[diskIdx].SizeGB = diskSize
+	}
+	err = replaceWorkloads(ctx, t.DeploymentDeployer.Deploy, without, resized, dl)
+	if err != nil {
+		return workloads.VM{}, errors.Wrapf(err, "failed to resize vm on node %d", dl.NodeID)
+	}
+	vm, err = t.State.LoadVMFromGrid(dl.NodeID, name, dl.Name)
+	if err != nil {
+		return workloads.VM{}, err
+	}
+	ForgetSSHHosts(vm.ComputedIP, vm.ComputedIP6, vm.YggIP, vm.IP)
+	return vm, nil
+}
+
+// deployFunc deploys a deployment, like the deployment deployer does
+type deployFunc func(ctx context.Context, dl *workloads.Deployment) error
+
+// replaceWorkloads removes workloads from a deployment by deploying it without them, then deploys it with their new spec.
+// if the new spec fails, the previous deployment is deployed back so the workloads are not left removed.
+// it is not stopped by interrupts, which would leave the workloads removed
+func replaceWorkloads(ctx context.Context, deploy deployFunc, without, with, previous workloads.Deployment) error {
+	ctx = uninterruptible{ctx}
+	log.Info().Msgf("removing workloads %v from deployment %s", missingWorkloads(without, with), with.Name)
+	if err := deploy(ctx, &without); err != nil {
+		return errors.Wrap(err, "failed to remove workloads")
+	}
+	with.ContractID, previous.ContractID = without.ContractID, without.ContractID
+	with.NodeDeploymentID, previous.NodeDeploymentID = without.NodeDeploymentID, without.NodeDeploymentID
+
+	log.Info().Msgf("deploying workloads %v of deployment %s", missingWorkloads(without, with), with.Name)
+	err := deploy(ctx, &with)
+	if err == nil {
+		return nil
+	}
+	log.Error().Err(err).Msg("failed to deploy the new workloads, deploying the previous ones back")
+	if perr := deploy(ctx, &previous); perr != nil {
+		return errkind.Wrap(errkind.PartialFailure, errors.Wrapf(err, "workloads %v are removed from deployment %s, deploying them back failed: %s", missingWorkloads(without, previous), with.Name, perr))
+	}
+	return errors.Wrap(err, "the previous workloads were deployed back")
+}
+
+// missingWorkloads returns the names of the workloads of with that are not in without
+func missingWorkloads(without, with workloads.Deployment) []string {
+	names := map[string]bool{}
+	for _, name := range deploymentWorkloadNames(without) {
+		names[name] = true
+	}
+	var missing []string
+	for _, name := range deploymentWorkloadNames(with) {
+		if !names[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+func deploymentWorkloadNames(dl workloads.Deployment) []string {
+	var names []string
+	for _, vm := range dl.Vms {
+		names = append(names, vm.Name)
+	}
+	for _, disk := range dl.Disks {
+		names = append(names, disk.Name)
+	}
+	for _, zdb := range dl.Zdbs {
+		names = append(names, zdb.Name)
+	}
+	for _, qsfs := range dl.QSFS {
+		names = append(names, qsfs.Name)
+	}
+	return names
+}
+
+// checkResizeCapacity checks a node has enough free memory and ssd storage for extra resources
+func checkResizeCapacity(node types.NodeWithNestedCapacity, extraMRU, extraSRU gridtypes.Unit) error {
+	total, used := node.Capacity.Total, node.Capacity.Used
+	if extraMRU > 0 && used.MRU+extraMRU > total.MRU {
+		return errkind.Errorf(errkind.NoCapacity, "node %d has not enough free memory, needs %d mb more", node.NodeID, extraMRU/gridtypes.Megabyte)
+	}
+	if extraSRU > 0 && used.SRU+extraSRU > total.SRU {
+		return errkind.Errorf(errkind.NoCapacity, "node %d has not enough free ssd storage, needs %d gb more", node.NodeID, extraSRU/gridtypes.Gigabyte)
+	}
+	return nil
+}
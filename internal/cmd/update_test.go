@@ -0,0 +1,163 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/grid_proxy_server/pkg/types"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestCheckResizeCapacity(t *testing.T) {
+	node := types.NodeWithNestedCapacity{
+		NodeID: 14,
+		Capacity: types.CapacityResult{
+			Total: types.Capacity{MRU: 16 * gridtypes.Gigabyte, SRU: 512 * gridtypes.Gigabyte},
+			Used:  types.Capacity{MRU: 12 * gridtypes.Gigabyte, SRU: 500 * gridtypes.Gigabyte},
+		},
+	}
+
+	assert.NoError(t, checkResizeCapacity(node, 4*gridtypes.Gigabyte, 12*gridtypes.Gigabyte))
+	assert.NoError(t, checkResizeCapacity(node, 0, 0))
+	err := checkResizeCapacity(node, 5*gridtypes.Gigabyte, 0)
+	assert.Error(t, err)
+	assert.Equal(t, errkind.NoCapacity, errkind.Of(err))
+	assert.Error(t, checkResizeCapacity(node, 0, 13*gridtypes.Gigabyte))
+}
+
+func TestReplaceWorkloads(t *testing.T) {
+	previous := workloads.Deployment{
+		Name:  "vm",
+		Vms:   []workloads.VM{{Name: "vm", CPU: 1}},
+		Disks: []workloads.Disk{{Name: "vmdisk", SizeGB: 10}},
+	}
+	without := previous
+	without.Vms = nil
+	resized := previous
+	resized.Vms = []workloads.VM{{Name: "vm", CPU: 2}}
+
+	// fakeDeploy records the cpu of the deployed vm, 0 if it is removed, and fails on the given calls
+	fakeDeploy := func(deployed *[]int, failOn ...int) deployFunc {
+		return func(ctx context.Context, dl *workloads.Deployment) error {
+			call := len(*deployed)
+			cpu := 0
+			if len(dl.Vms) != 0 {
+				cpu = dl.Vms[0].CPU
+			}
+			*deployed = append(*deployed, cpu)
+			for _, fail := range failOn {
+				if call == fail {
+					return errors.New("node error")
+				}
+			}
+			dl.ContractID = 5
+			return nil
+		}
+	}
+
+	t.Run("new spec deployed", func(t *testing.T) {
+		var deployed []int
+		assert.NoError(t, replaceWorkloads(context.Background(), fakeDeploy(&deployed), without, resized, previous))
+		assert.Equal(t, []int{0, 2}, deployed)
+	})
+	t.Run("previous spec deployed back", func(t *testing.T) {
+		var deployed []int
+		err := replaceWorkloads(context.Background(), fakeDeploy(&deployed, 1), without, resized, previous)
+		assert.ErrorContains(t, err, "node error")
+		assert.Equal(t, errkind.Unknown, errkind.Of(err))
+		assert.Equal(t, []int{0, 2, 1}, deployed)
+	})
+	t.Run("previous spec failed", func(t *testing.T) {
+		var deployed []int
+		err := replaceWorkloads(context.Background(), fakeDeploy(&deployed, 1, 2), without, resized, previous)
+		assert.ErrorContains(t, err, "workloads [vm] are removed")
+		assert.Equal(t, errkind.PartialFailure, errkind.Of(err))
+	})
+	t.Run("removing failed", func(t *testing.T) {
+		var deployed []int
+		err := replaceWorkloads(context.Background(), fakeDeploy(&deployed, 0), without, resized, previous)
+		assert.ErrorContains(t, err, "failed to remove workloads")
+		assert.Equal(t, []int{0}, deployed)
+	})
+}
+
+func TestSetGatewayWorkload(t *testing.T) {
+	data, err := json.Marshal(zos.GatewayNameProxy{GatewayBase: zos.GatewayBase{Backends: []zos.Backend{"http://185.206.122.10:9000"}}, Name: "site"})
+	assert.NoError(t, err)
+	dl := gridtypes.Deployment{ContractID: 20, Workloads: []gridtypes.Workload{{Name: "site", Type: zos.GatewayNameProxyType, Data: data}}}
+
+	updated, err := setGatewayWorkload(dl, "site", []zos.Backend{"185.206.122.20:443"}, true)
+	assert.NoError(t, err)
+	var proxy zos.GatewayNameProxy
+	assert.NoError(t, json.Unmarshal(updated.Workloads[0].Data, &proxy))
+	assert.Equal(t, []zos.Backend{"185.206.122.20:443"}, proxy.Backends)
+	assert.True(t, proxy.TLSPassthrough)
+	assert.Equal(t, "site", proxy.Name)
+	assert.Equal(t, json.RawMessage(data), dl.Workloads[0].Data)
+
+	_, err = setGatewayWorkload(dl, "other", nil, false)
+	assert.Equal(t, errkind.NotFound, errkind.Of(err))
+}
+
+func TestRecreateNodeWorkloads(t *testing.T) {
+	gateway := func(backend string) gridtypes.Workload {
+		data, err := json.Marshal(zos.GatewayNameProxy{GatewayBase: zos.GatewayBase{Backends: []zos.Backend{zos.Backend(backend)}}, Name: "site"})
+		assert.NoError(t, err)
+		return gridtypes.Workload{Name: "site", Type: zos.GatewayNameProxyType, Data: data, Result: gridtypes.Result{State: gridtypes.StateOk}}
+	}
+	previous := gridtypes.Deployment{ContractID: 20, Workloads: []gridtypes.Workload{gateway("http://185.206.122.10:9000")}}
+	updated := gridtypes.Deployment{ContractID: 20, Workloads: []gridtypes.Workload{gateway("http://185.206.122.20:9000")}}
+	names := map[string]bool{"site": true}
+
+	// fakeDeploy records the backend of the deployed gateway, empty if it is removed, and fails on the given calls
+	fakeDeploy := func(deployed *[]string, failOn ...int) zosDeployFunc {
+		return func(ctx context.Context, oldDeployments map[uint32]uint64, newDeployments map[uint32]gridtypes.Deployment, solutionProviders map[uint32]*uint64) (map[uint32]uint64, error) {
+			call := len(*deployed)
+			backend := ""
+			for _, wl := range newDeployments[11].Workloads {
+				var proxy zos.GatewayNameProxy
+				assert.NoError(t, json.Unmarshal(wl.Data, &proxy))
+				assert.Equal(t, gridtypes.Result{}, wl.Result)
+				backend = string(proxy.Backends[0])
+			}
+			*deployed = append(*deployed, backend)
+			for _, fail := range failOn {
+				if call == fail {
+					return oldDeployments, errors.New("node error")
+				}
+			}
+			return oldDeployments, nil
+		}
+	}
+
+	t.Run("updated gateway added", func(t *testing.T) {
+		var deployed []string
+		assert.NoError(t, recreateNodeWorkloads(context.Background(), fakeDeploy(&deployed), 11, updated, previous, names))
+		assert.Equal(t, []string{"", "http://185.206.122.20:9000"}, deployed)
+	})
+	t.Run("previous gateway added back", func(t *testing.T) {
+		var deployed []string
+		err := recreateNodeWorkloads(context.Background(), fakeDeploy(&deployed, 1), 11, updated, previous, names)
+		assert.ErrorContains(t, err, "the previous workloads were added back")
+		assert.Equal(t, errkind.Unknown, errkind.Of(err))
+		assert.Equal(t, []string{"", "http://185.206.122.20:9000", "http://185.206.122.10:9000"}, deployed)
+	})
+	t.Run("retried spec added", func(t *testing.T) {
+		var deployed []string
+		assert.NoError(t, recreateNodeWorkloads(context.Background(), fakeDeploy(&deployed, 1), 11, previous, previous, names))
+		assert.Equal(t, []string{"", "http://185.206.122.10:9000", "http://185.206.122.10:9000"}, deployed)
+	})
+	t.Run("previous gateway failed", func(t *testing.T) {
+		var deployed []string
+		err := recreateNodeWorkloads(context.Background(), fakeDeploy(&deployed, 1, 2), 11, updated, previous, names)
+		assert.ErrorContains(t, err, "workloads [site] are removed")
+		assert.Equal(t, errkind.PartialFailure, errkind.Of(err))
+	})
+}
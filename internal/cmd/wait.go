@@ -0,0 +1,152 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/threefoldtech/grid3-go/workloads"
+)
+
+// ErrProbeTimeout is returned when a deployment is not ready before the wait timeout
+var ErrProbeTimeout = errors.New("timed out waiting for deployment to be ready")
+
+// probeInterval is the time between probe attempts
+var probeInterval = 10 * time.Second
+
+const probeDialTimeout = 5 * time.Second
+
+// WaitForVM waits until ssh answers on the vm reachable ip
+func WaitForVM(ctx context.Context, vm workloads.VM, timeout time.Duration) error {
+	address, err := sshAddress(vm.ComputedIP, vm.ComputedIP6, vm.YggIP, vm.IP)
+	if err != nil {
+		return errors.Wrapf(err, "vm %s is not reachable", vm.Name)
+	}
+	return waitFor(ctx, fmt.Sprintf("ssh on %s", address), timeout, func() error {
+		return probeSSH(net.JoinHostPort(address, "22"))
+	})
+}
+
+// WaitForK8sNode waits until ssh answers on the kubernetes node reachable ip
+func WaitForK8sNode(ctx context.Context, node workloads.K8sNode, timeout time.Duration) error {
+	address, err := sshAddress(node.ComputedIP, node.ComputedIP6, node.YggIP, node.IP)
+	if err != nil {
+		return errors.Wrapf(err, "kubernetes node %s is not reachable", node.Name)
+	}
+	return waitFor(ctx, fmt.Sprintf("ssh on %s", address), timeout, func() error {
+		return probeSSH(net.JoinHostPort(address, "22"))
+	})
+}
+
+// WaitForK3s waits until k3s on the master reports the expected number of ready nodes
+// the master is new or recreated, so a key saved for its ip is forgotten first
+func WaitForK3s(ctx context.Context, master workloads.K8sNode, identity string, nodes int, timeout time.Duration) error {
+	address, err := sshAddress(master.ComputedIP, master.ComputedIP6, master.YggIP, master.IP)
+	if err != nil {
+		return errors.Wrapf(err, "master %s is not reachable", master.Name)
+	}
+	ForgetSSHHosts(address)
+	target := SSHTarget{Name: master.Name, Address: address}
+	return waitFor(ctx, fmt.Sprintf("%d kubernetes nodes to be ready", nodes), timeout, func() error {
+		out, err := SSHOutput(target, identity, "root", "kubectl", "get", "nodes", "--no-headers")
+		if err != nil {
+			return err
+		}
+		ready := countReadyNodes(string(out))
+		if ready < nodes {
+			return fmt.Errorf("%d/%d nodes ready", ready, nodes)
+		}
+		return nil
+	})
+}
+
+// WaitForHTTP waits until a gateway fqdn serves http requests
+func WaitForHTTP(ctx context.Context, fqdn string, timeout time.Duration) error {
+	url := fmt.Sprintf("https://%s", fqdn)
+	return waitFor(ctx, url, timeout, func() error {
+		return probeHTTP(url)
+	})
+}
+
+// waitFor runs a probe until it succeeds, the timeout is reached or ctx is done, logging progress
+func waitFor(ctx context.Context, description string, timeout time.Duration, probe func() error) error {
+	log.Info().Msgf("waiting for %s", description)
+	start := time.Now()
+	for {
+		err := probe()
+		if err == nil {
+			log.Info().Msgf("%s is ready after %s", description, time.Since(start).Round(time.Second))
+			return nil
+		}
+		elapsed := time.Since(start)
+		if elapsed+probeInterval > timeout {
+			return errors.Wrapf(ErrProbeTimeout, "%s not ready after %s: %s", description, timeout, err)
+		}
+		log.Info().Msgf("still waiting for %s (%s elapsed): %s", description, elapsed.Round(time.Second), err)
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "stopped waiting for %s", description)
+		case <-time.After(probeInterval):
+		}
+	}
+}
+
+// probeSSH checks an ssh server answers with its version banner
+func probeSSH(hostPort string) error {
+	conn, err := net.DialTimeout("tcp", hostPort, probeDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetReadDeadline(time.Now().Add(probeDialTimeout)); err != nil {
+		return err
+	}
+	banner, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return errors.Wrap(err, "could not read ssh banner")
+	}
+	if !strings.HasPrefix(banner, "SSH-") {
+		return fmt.Errorf("unexpected ssh banner %q", strings.TrimSpace(banner))
+	}
+	return nil
+}
+
+// probeHTTP checks a url answers without a gateway error
+func probeHTTP(url string) error {
+	client := http.Client{
+		Timeout: probeDialTimeout,
+		Transport: &http.Transport{
+			// certificates may not be issued yet, only the backend response matters
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("got status %s", resp.Status)
+	}
+	return nil
+}
+
+// countReadyNodes counts ready nodes in kubectl get nodes output
+func countReadyNodes(out string) int {
+	ready := 0
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && fields[1] == "Ready" {
+			ready++
+		}
+	}
+	return ready
+}
@@ -0,0 +1,80 @@
+// Package cmd for handling commands
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitFor(t *testing.T) {
+	defer func(interval time.Duration) { probeInterval = interval }(probeInterval)
+	probeInterval = time.Millisecond
+
+	attempts := 0
+	err := waitFor(context.Background(), "probe", time.Second, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	err = waitFor(context.Background(), "probe", 10*time.Millisecond, func() error {
+		return errors.New("not ready")
+	})
+	assert.ErrorIs(t, err, ErrProbeTimeout)
+}
+
+func TestProbeSSH(t *testing.T) {
+	serve := func(banner string) string {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		t.Cleanup(func() { listener.Close() })
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			_, _ = conn.Write([]byte(banner))
+		}()
+		return listener.Addr().String()
+	}
+
+	assert.NoError(t, probeSSH(serve("SSH-2.0-OpenSSH_8.9\r\n")))
+	assert.Error(t, probeSSH(serve("HTTP/1.1 400 Bad Request\r\n")))
+}
+
+func TestProbeHTTP(t *testing.T) {
+	ok := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ok.Close()
+	assert.NoError(t, probeHTTP(ok.URL))
+
+	badGateway := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer badGateway.Close()
+	assert.Error(t, probeHTTP(badGateway.URL))
+}
+
+func TestCountReadyNodes(t *testing.T) {
+	out := strings.Join([]string{
+		"master    Ready      control-plane,master   5m    v1.26.0+k3s1",
+		"worker0   NotReady   <none>                 1m    v1.26.0+k3s1",
+		"worker1   Ready      <none>                 2m    v1.26.0+k3s1",
+		"",
+	}, "\n")
+	assert.Equal(t, 2, countReadyNodes(out))
+}
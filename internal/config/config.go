@@ -3,14 +3,19 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/pkg/errors"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
 )
 
 const configFile = ".tfgridconfig"
 
+var profileRegex = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
 // Config struct that holds user configuration
 type Config struct {
 	Mnemonics string `json:"mnemonics"`
@@ -55,18 +60,36 @@ func (c *Config) Load(path string) error {
 
 // GetConfigPath returns the path of tf-grid configuration file
 func GetConfigPath() (string, error) {
+	return GetProfileConfigPath("")
+}
+
+// GetProfileConfigPath returns the path of the configuration file of a named profile,
+// or of the default configuration file if profile is empty
+func GetProfileConfigPath(profile string) (string, error) {
+	if profile != "" && !profileRegex.MatchString(profile) {
+		return "", errkind.Errorf(errkind.Validation, "invalid profile name %s, it can only contain letters, digits, - and _", profile)
+	}
 	configDir, err := os.UserConfigDir()
 	if err != nil {
 		return "", errors.Wrap(err, "could not get configuration directory")
 	}
-	path := filepath.Join(configDir, configFile)
+	name := configFile
+	if profile != "" {
+		name = fmt.Sprintf("%s-%s", configFile, profile)
+	}
+	path := filepath.Join(configDir, name)
 	return path, nil
 }
 
 // GetUserConfig returns user configuration
 func GetUserConfig() (Config, error) {
+	return GetProfileConfig("")
+}
+
+// GetProfileConfig returns the configuration of a named profile, or the default configuration if profile is empty
+func GetProfileConfig(profile string) (Config, error) {
 
-	path, err := GetConfigPath()
+	path, err := GetProfileConfigPath(profile)
 	if err != nil {
 		return Config{}, errors.Wrap(err, "failed to get configuration file")
 	}
@@ -74,7 +97,11 @@ func GetUserConfig() (Config, error) {
 	cfg := Config{}
 	err = cfg.Load(path)
 	if err != nil {
-		return Config{}, errors.Wrap(err, "failed to load configuration try to login again using tf-grid login")
+		login := "tf-grid login"
+		if profile != "" {
+			login = fmt.Sprintf("tf-grid login --profile %s", profile)
+		}
+		return Config{}, errkind.Wrap(errkind.Config, errors.Wrapf(err, "failed to load configuration try to login again using %s", login))
 	}
 	return cfg, nil
 }
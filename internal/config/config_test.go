@@ -93,3 +93,20 @@ func TestLoad(t *testing.T) {
 		assert.Empty(t, c)
 	})
 }
+
+func TestGetProfileConfigPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	defaultPath, err := GetProfileConfigPath("")
+	assert.NoError(t, err)
+	assert.Equal(t, ".tfgridconfig", filepath.Base(defaultPath))
+
+	path, err := GetProfileConfigPath("prod")
+	assert.NoError(t, err)
+	assert.Equal(t, ".tfgridconfig-prod", filepath.Base(path))
+	assert.Equal(t, filepath.Dir(defaultPath), filepath.Dir(path))
+
+	_, err = GetProfileConfigPath("../prod")
+	assert.Error(t, err)
+}
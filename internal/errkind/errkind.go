@@ -0,0 +1,96 @@
+// Package errkind classifies command errors so scripts can tell failures apart by exit code
+package errkind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Kind is a class of command failure with its own exit code
+type Kind int
+
+// kinds of failures, their exit codes are documented in the README
+const (
+	Unknown Kind = iota
+	Validation
+	WaitTimeout
+	WorkloadError
+	NotFound
+	NoCapacity
+	Config
+	Chain
+	RMBTimeout
+	PartialFailure
+	Interrupted
+)
+
+var kinds = map[Kind]struct {
+	name     string
+	exitCode int
+}{
+	Unknown:        {"unknown", 1},
+	Validation:     {"validation", 2},
+	WaitTimeout:    {"wait_timeout", 3},
+	WorkloadError:  {"workload_error", 4},
+	NotFound:       {"not_found", 5},
+	NoCapacity:     {"no_capacity", 6},
+	Config:         {"config", 7},
+	Chain:          {"chain", 8},
+	RMBTimeout:     {"rmb_timeout", 9},
+	PartialFailure: {"partial_failure", 10},
+	Interrupted:    {"interrupted", 130},
+}
+
+// String returns the name of the kind used in json error bodies
+func (k Kind) String() string {
+	return kinds[k].name
+}
+
+// ExitCode returns the exit code of the kind
+func (k Kind) ExitCode() int {
+	return kinds[k].exitCode
+}
+
+// Error is an error of a known kind
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap marks an error with a kind, it returns nil if err is nil
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// Errorf formats an error of a kind
+func Errorf(kind Kind, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// Of returns the outermost kind an error is marked with.
+// unmarked errors of timed out or canceled calls are rmb timeouts or interruptions
+func Of(err error) Kind {
+	var kindErr *Error
+	if errors.As(err, &kindErr) {
+		return kindErr.Kind
+	}
+	if errors.Is(err, context.Canceled) {
+		return Interrupted
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return RMBTimeout
+	}
+	return Unknown
+}
@@ -0,0 +1,38 @@
+package errkind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOf(t *testing.T) {
+	assert.Equal(t, Unknown, Of(nil))
+	assert.Equal(t, Unknown, Of(errors.New("failed")))
+
+	notFound := Errorf(NotFound, "no vm with name %s found", "vm")
+	assert.Equal(t, NotFound, Of(notFound))
+	assert.Equal(t, NotFound, Of(pkgerrors.Wrap(notFound, "failed to get vm")))
+	assert.Equal(t, NotFound, Of(fmt.Errorf("failed to get vm: %w", notFound)))
+	assert.Equal(t, PartialFailure, Of(Wrap(PartialFailure, notFound)))
+
+	assert.Equal(t, RMBTimeout, Of(pkgerrors.Wrap(context.DeadlineExceeded, "could not get deployment")))
+	assert.Equal(t, Interrupted, Of(context.Canceled))
+	assert.Nil(t, Wrap(Chain, nil))
+}
+
+func TestExitCodes(t *testing.T) {
+	codes := map[int]Kind{}
+	for kind := Unknown; kind <= Interrupted; kind++ {
+		assert.NotEmpty(t, kind.String())
+		other, ok := codes[kind.ExitCode()]
+		assert.False(t, ok, "%s and %s have the same exit code", kind, other)
+		codes[kind.ExitCode()] = kind
+	}
+	assert.Equal(t, 3, WaitTimeout.ExitCode())
+	assert.Equal(t, 4, WorkloadError.ExitCode())
+}
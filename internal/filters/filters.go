@@ -1,42 +1,135 @@
 package filters
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/threefoldtech/grid3-go/workloads"
 	"github.com/threefoldtech/grid_proxy_server/pkg/client"
 	"github.com/threefoldtech/grid_proxy_server/pkg/types"
+	"github.com/threefoldtech/tf-grid-cli/internal/errkind"
 )
 
-func GetAvailableNode(client client.Client, filter types.NodeFilter) (uint32, error) {
-	nodes, _, err := client.Nodes(filter, types.Limit{})
+const nodesPageSize = 100
+
+// NodeChecker checks a candidate node answers before it is picked for a deployment
+type NodeChecker func(ctx context.Context, nodeID uint32) error
+
+// nodeCheckTimeout is the time a candidate node has to answer its check
+var nodeCheckTimeout = 5 * time.Second
+
+func GetAvailableNode(ctx context.Context, client client.Client, filter types.NodeFilter, checker NodeChecker) (uint32, error) {
+	nodes, err := GetAvailableNodes(ctx, client, filter, 1, checker)
 	if err != nil {
 		return 0, err
 	}
+	return nodes[0], nil
+}
+
+// GetAvailableNodes returns count nodes matching a filter, nodes failing the checker are skipped if it is set
+func GetAvailableNodes(ctx context.Context, client client.Client, filter types.NodeFilter, count int, checker NodeChecker) ([]uint32, error) {
+	nodes, _, err := client.Nodes(filter, types.Limit{})
+	if err != nil {
+		return nil, err
+	}
 	if len(nodes) == 0 {
-		var filterStringBuilder strings.Builder
-		if filter.FarmIDs != nil {
-			fmt.Fprintf(&filterStringBuilder, "farmIDs: %v, ", filter.FarmIDs)
-		}
-		if filter.FreeMRU != nil {
-			fmt.Fprintf(&filterStringBuilder, "mru: %d, ", *filter.FreeMRU)
+		return nil, errkind.Errorf(errkind.NoCapacity, "no node with free resources available using node filter: %s", filterString(filter))
+	}
+	if len(nodes) < count {
+		return nil, errkind.Errorf(errkind.NoCapacity, "found %d nodes with free resources, but %d are needed using node filter: %s", len(nodes), count, filterString(filter))
+	}
+
+	var nodeIDs []uint32
+	for _, node := range nodes {
+		nodeIDs = append(nodeIDs, uint32(node.NodeID))
+	}
+	if checker != nil {
+		nodeIDs = checkNodesUntil(ctx, nodeIDs, count, checker)
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
-		if filter.FreeSRU != nil {
-			fmt.Fprintf(&filterStringBuilder, "sru: %d, ", *filter.FreeSRU)
+		if len(nodeIDs) < count {
+			return nil, errkind.Errorf(errkind.NoCapacity, "found %d nodes with free resources, but only %d of them answered while %d are needed using node filter: %s", len(nodes), len(nodeIDs), count, filterString(filter))
 		}
-		if filter.FreeIPs != nil {
-			fmt.Fprintf(&filterStringBuilder, "freeips: %d, ", *filter.FreeIPs)
+	}
+	return nodeIDs[:count], nil
+}
+
+// CheckNode checks a node answers with a short timeout, or until ctx is done
+func CheckNode(ctx context.Context, node uint32, checker NodeChecker) error {
+	ctx, cancel := context.WithTimeout(ctx, nodeCheckTimeout)
+	defer cancel()
+	return checker(ctx, node)
+}
+
+// checkNodesUntil checks nodes in order, in batches of the number still needed, until count of them answered
+// and returns the ones that answered. it stops once ctx is done
+func checkNodesUntil(ctx context.Context, nodes []uint32, count int, checker NodeChecker) []uint32 {
+	var up []uint32
+	for len(nodes) != 0 && len(up) < count && ctx.Err() == nil {
+		batch := count - len(up)
+		if batch > len(nodes) {
+			batch = len(nodes)
 		}
-		if filter.Domain != nil {
-			fmt.Fprintf(&filterStringBuilder, "domain: %t, ", *filter.Domain)
+		up = append(up, checkNodes(ctx, nodes[:batch], checker)...)
+		nodes = nodes[batch:]
+	}
+	return up
+}
+
+// checkNodes checks nodes concurrently and returns the ones that answered, in the same order
+func checkNodes(ctx context.Context, nodes []uint32, checker NodeChecker) []uint32 {
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node uint32) {
+			defer wg.Done()
+			errs[i] = CheckNode(ctx, node, checker)
+		}(i, node)
+	}
+	wg.Wait()
+
+	var up []uint32
+	for i, node := range nodes {
+		if errs[i] != nil {
+			log.Warn().Err(errs[i]).Msgf("skipping node %d, it did not answer", node)
+			continue
 		}
-		filterString := filterStringBuilder.String()
-		return 0, fmt.Errorf("no node with free resources available using node filter: %s", filterString[:len(filterString)-2])
+		up = append(up, node)
 	}
+	return up
+}
 
-	node := uint32(nodes[0].NodeID)
-	return node, nil
+func filterString(filter types.NodeFilter) string {
+	var filterStringBuilder strings.Builder
+	if filter.FarmIDs != nil {
+		fmt.Fprintf(&filterStringBuilder, "farmIDs: %v, ", filter.FarmIDs)
+	}
+	if filter.FreeMRU != nil {
+		fmt.Fprintf(&filterStringBuilder, "mru: %d, ", *filter.FreeMRU)
+	}
+	if filter.FreeSRU != nil {
+		fmt.Fprintf(&filterStringBuilder, "sru: %d, ", *filter.FreeSRU)
+	}
+	if filter.FreeHRU != nil {
+		fmt.Fprintf(&filterStringBuilder, "hru: %d, ", *filter.FreeHRU)
+	}
+	if filter.FreeIPs != nil {
+		fmt.Fprintf(&filterStringBuilder, "freeips: %d, ", *filter.FreeIPs)
+	}
+	if filter.Domain != nil {
+		fmt.Fprintf(&filterStringBuilder, "domain: %t, ", *filter.Domain)
+	}
+	if filter.Country != nil {
+		fmt.Fprintf(&filterStringBuilder, "country: %s, ", *filter.Country)
+	}
+	filterString := filterStringBuilder.String()
+	return strings.TrimSuffix(filterString, ", ")
 }
 
 func BuildK8sFilter(k8sNode workloads.K8sNode, farmID uint64, k8sNodesNum uint) types.NodeFilter {
@@ -47,7 +140,7 @@ func BuildK8sFilter(k8sNode workloads.K8sNode, farmID uint64, k8sNodesNum uint)
 		freeIPs = uint64(k8sNodesNum)
 	}
 
-	return buildGenericFilter(&freeMRUs, &freeSRUs, &freeIPs, []uint64{farmID}, nil)
+	return buildGenericFilter(&freeMRUs, &freeSRUs, nil, &freeIPs, []uint64{farmID}, nil)
 }
 
 func BuildVMFilter(vm workloads.VM, disk workloads.Disk, farmID uint64) types.NodeFilter {
@@ -58,22 +151,103 @@ func BuildVMFilter(vm workloads.VM, disk workloads.Disk, farmID uint64) types.No
 		freeIPs = 1
 	}
 	freeSRUs += uint64(disk.SizeGB)
-	return buildGenericFilter(&freeMRUs, &freeSRUs, &freeIPs, []uint64{farmID}, nil)
+	return buildGenericFilter(&freeMRUs, &freeSRUs, nil, &freeIPs, []uint64{farmID}, nil)
+}
+
+func BuildZDBFilter(zdb workloads.ZDB, farmID uint64) types.NodeFilter {
+	return BuildZDBsFilter([]workloads.ZDB{zdb}, farmID)
+}
+
+func BuildZDBsFilter(zdbs []workloads.ZDB, farmID uint64) types.NodeFilter {
+	freeHRUs := uint64(0)
+	for _, zdb := range zdbs {
+		freeHRUs += uint64(zdb.Size)
+	}
+	return buildGenericFilter(nil, nil, &freeHRUs, nil, []uint64{farmID}, nil)
 }
 
 func BuildGatewayFilter(farmID uint64) types.NodeFilter {
 	domain := true
-	return buildGenericFilter(nil, nil, nil, []uint64{farmID}, &domain)
+	return buildGenericFilter(nil, nil, nil, nil, []uint64{farmID}, &domain)
 }
 
-func buildGenericFilter(mrus, srus, ips *uint64, farmIDs []uint64, domain *bool) types.NodeFilter {
+func buildGenericFilter(mrus, srus, hrus, ips *uint64, farmIDs []uint64, domain *bool) types.NodeFilter {
 	status := "up"
 	return types.NodeFilter{
 		Status:  &status,
 		FreeMRU: mrus,
 		FreeSRU: srus,
+		FreeHRU: hrus,
 		FreeIPs: ips,
 		FarmIDs: farmIDs,
 		Domain:  domain,
 	}
 }
+
+// BuildGatewaysFilter builds a filter for gateway nodes, farmID 0 and an empty country match any farm or country
+func BuildGatewaysFilter(farmID uint64, country string) types.NodeFilter {
+	domain := true
+	var farmIDs []uint64
+	if farmID != 0 {
+		farmIDs = []uint64{farmID}
+	}
+	filter := buildGenericFilter(nil, nil, nil, nil, farmIDs, &domain)
+	if country != "" {
+		filter.Country = &country
+	}
+	return filter
+}
+
+// GetNodes returns all nodes matching a filter, going through all proxy pages
+func GetNodes(client client.Client, filter types.NodeFilter) ([]types.Node, error) {
+	var nodes []types.Node
+	for page := uint64(1); ; page++ {
+		res, _, err := client.Nodes(filter, types.Limit{Size: nodesPageSize, Page: page})
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, res...)
+		if len(res) < nodesPageSize {
+			return nodes, nil
+		}
+	}
+}
+
+// GetGatewayNodeByDomain returns a gateway node matching a filter that serves the given domain,
+// nodes failing the checker are skipped if it is set
+func GetGatewayNodeByDomain(ctx context.Context, client client.Client, filter types.NodeFilter, domain string, checker NodeChecker) (uint32, error) {
+	nodes, err := GetNodes(client, filter)
+	if err != nil {
+		return 0, err
+	}
+	nodeIDs := domainNodes(nodes, domain)
+	if len(nodeIDs) == 0 {
+		return 0, errkind.Errorf(errkind.NoCapacity, "no gateway node serving domain %s available using node filter: %s", domain, filterString(filter))
+	}
+	if checker != nil {
+		found := len(nodeIDs)
+		nodeIDs = checkNodesUntil(ctx, nodeIDs, 1, checker)
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if len(nodeIDs) == 0 {
+			return 0, errkind.Errorf(errkind.NoCapacity, "found %d gateway nodes serving domain %s, but none of them answered using node filter: %s", found, domain, filterString(filter))
+		}
+	}
+	return nodeIDs[0], nil
+}
+
+// domainNodes returns the ids of the nodes serving the given domain
+func domainNodes(nodes []types.Node, domain string) []uint32 {
+	var nodeIDs []uint32
+	for _, node := range nodes {
+		if sameDomain(node.PublicConfig.Domain, domain) {
+			nodeIDs = append(nodeIDs, uint32(node.NodeID))
+		}
+	}
+	return nodeIDs
+}
+
+func sameDomain(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
@@ -0,0 +1,101 @@
+package filters
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/grid3-go/workloads"
+	"github.com/threefoldtech/grid_proxy_server/pkg/types"
+)
+
+func TestBuildZDBFilter(t *testing.T) {
+	zdb := workloads.ZDB{
+		Name: "test",
+		Size: 10,
+		Mode: "user",
+	}
+	filter := BuildZDBFilter(zdb, 1)
+
+	assert.Equal(t, *filter.Status, "up")
+	assert.Equal(t, *filter.FreeHRU, uint64(10))
+	assert.Nil(t, filter.FreeSRU)
+	assert.Nil(t, filter.FreeMRU)
+	assert.Equal(t, filter.FarmIDs, []uint64{1})
+}
+
+func TestBuildGatewaysFilter(t *testing.T) {
+	filter := BuildGatewaysFilter(0, "")
+	assert.True(t, *filter.Domain)
+	assert.Nil(t, filter.FarmIDs)
+	assert.Nil(t, filter.Country)
+
+	filter = BuildGatewaysFilter(1, "Belgium")
+	assert.Equal(t, filter.FarmIDs, []uint64{1})
+	assert.Equal(t, *filter.Country, "Belgium")
+}
+
+func TestSameDomain(t *testing.T) {
+	assert.True(t, sameDomain("gent01.dev.grid.tf", "gent01.dev.grid.tf"))
+	assert.True(t, sameDomain("Gent01.dev.grid.tf.", "gent01.dev.grid.tf"))
+	assert.False(t, sameDomain("gent01.dev.grid.tf", "gent02.dev.grid.tf"))
+}
+
+func TestDomainNodes(t *testing.T) {
+	nodes := []types.Node{
+		{NodeID: 1, PublicConfig: types.PublicConfig{Domain: "gent01.dev.grid.tf"}},
+		{NodeID: 2, PublicConfig: types.PublicConfig{Domain: "gent02.dev.grid.tf"}},
+		{NodeID: 3, PublicConfig: types.PublicConfig{Domain: "Gent01.dev.grid.tf."}},
+	}
+	assert.Equal(t, []uint32{1, 3}, domainNodes(nodes, "gent01.dev.grid.tf"))
+	assert.Nil(t, domainNodes(nodes, "gent03.dev.grid.tf"))
+}
+
+func TestCheckNodes(t *testing.T) {
+	checker := func(ctx context.Context, nodeID uint32) error {
+		if nodeID == 2 {
+			return errors.New("node did not answer")
+		}
+		if nodeID == 4 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}
+	defer func(timeout time.Duration) { nodeCheckTimeout = timeout }(nodeCheckTimeout)
+	nodeCheckTimeout = 10 * time.Millisecond
+	assert.Equal(t, []uint32{1, 3, 5}, checkNodes(context.Background(), []uint32{1, 2, 3, 4, 5}, checker))
+	assert.Nil(t, checkNodes(context.Background(), []uint32{2}, checker))
+}
+
+func TestCheckNodesUntil(t *testing.T) {
+	var mu sync.Mutex
+	var checked []uint32
+	checker := func(ctx context.Context, nodeID uint32) error {
+		mu.Lock()
+		defer mu.Unlock()
+		checked = append(checked, nodeID)
+		if nodeID == 2 {
+			return errors.New("node did not answer")
+		}
+		return nil
+	}
+
+	assert.Equal(t, []uint32{1}, checkNodesUntil(context.Background(), []uint32{1, 2, 3, 4, 5}, 1, checker))
+	assert.Equal(t, []uint32{1}, checked)
+
+	checked = nil
+	assert.Equal(t, []uint32{1, 3}, checkNodesUntil(context.Background(), []uint32{1, 2, 3, 4, 5}, 2, checker))
+	assert.ElementsMatch(t, []uint32{1, 2, 3}, checked)
+
+	assert.Equal(t, []uint32{1, 3}, checkNodesUntil(context.Background(), []uint32{1, 2, 3}, 4, checker))
+
+	checked = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Nil(t, checkNodesUntil(ctx, []uint32{1, 2, 3}, 1, checker))
+	assert.Nil(t, checked)
+}